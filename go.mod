@@ -8,3 +8,7 @@ require (
 )
 
 require launchpad.net/hcre v0.0.0-20241130145909-c832018180b1
+
+require github.com/ulikunitz/xz v0.5.17
+
+require github.com/klauspost/compress v1.17.11
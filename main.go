@@ -2,13 +2,16 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 
+	"github.com/jakewnuk/ptt/pkg/document"
 	"github.com/jakewnuk/ptt/pkg/format"
 	"github.com/jakewnuk/ptt/pkg/models"
 	"github.com/jakewnuk/ptt/pkg/transform"
@@ -22,6 +25,15 @@ var retain models.FileArgumentFlag
 var remove models.FileArgumentFlag
 var readFiles models.FileArgumentFlag
 var readURLs models.FileArgumentFlag
+var ntdsFiles models.FileArgumentFlag
+var ntdsPotfiles models.FileArgumentFlag
+var includeGlobs models.FileArgumentFlag
+var excludeGlobs models.FileArgumentFlag
+var crawlInclude models.FileArgumentFlag
+var crawlExclude models.FileArgumentFlag
+var httpHeaders models.FileArgumentFlag
+var readDocuments models.FileArgumentFlag
+var jsonPaths models.FileArgumentFlag
 var transformationFiles models.FileArgumentFlag
 var templateFiles models.FileArgumentFlag
 var intRange models.IntRange
@@ -56,11 +68,13 @@ func main() {
 			"decode":                                "Transforms input by HTML and Unicode escape decoding.",
 			"hex":                                   "Transforms input by encoding strings into $HEX[...] format.",
 			"dehex":                                 "Transforms input by decoding $HEX[...] formatted strings.",
-			"mask -rm [uldsb] -v":                   "Transforms input by masking characters with provided mask.",
+			"mask -rm [uldsbr] -v":                  "Transforms input by masking characters with provided mask.",
 			"mask-remove -rm [uldsb]":               "Transforms input by removing characters with provided mask.",
+			"mask-hex -rm [uldsbr]":                 "Transforms input by creating masks for use with the hashcat --hex-charset option.",
 			"mask-retain -rm [uldsb] -tf [file] -v": "Transforms input by creating masks that still retain strings from file.",
+			"mask-retain-combinations -tf [file]":   "Transforms input by creating masks for every combination of retained strings from file.",
 			"mask-pop -rm [uldsbt]":                 "Transforms input by 'popping' tokens from character boundaries using the provided mask.",
-			"mask-match -tf [file]":                 "Transforms input by keeping only strings with matching masks from a mask file.",
+			"mask-match -tf [file] -v":              "Transforms input by keeping only strings with matching masks, partial masks, wildcards, or regular expressions from a mask file.",
 			"swap-single -tf [file]":                "Transforms input by swapping tokens once per string per replacement with exact matches from a ':' separated file.",
 			"mask-swap -tf [file]":                  "Transforms input by swapping tokens from a mask/partial mask input and a transformation file of tokens.",
 			"passphrase -w [words]":                 "Transforms input by generating passphrases from sentences with a given number of words.",
@@ -69,6 +83,7 @@ func main() {
 			"regram -w [words]":                     "Transforms input by 'regramming' sentences into new n-grams with a given number of words.",
 			"rule-apply -tf [file]":                 "Transforms input by applying rules to strings using the HCRE library.",
 			"rule-simplify":                         "Transforms input by simplifying rules to efficient equivalents using the HCRE library.",
+			"markov":                                "Transforms input into per-position character and bigram frequency statistics.",
 		}
 
 		// Sort and print transformation modes
@@ -90,24 +105,62 @@ func main() {
 	verbose2 := flag.Bool("vv", false, "Show statistics output when possible.")
 	verbose3 := flag.Bool("vvv", false, "Show verbose statistics output when possible.")
 	minimum := flag.Int("m", 0, "Minimum numerical frequency to include in output.")
+	minimumEntropy := flag.Float64("minentropy", 0, "Minimum entropy in bits to include in output. Uses -tf files as a dictionary if provided.")
 	markDownOutput := flag.Bool("md", false, "If Markdown format should be used for output instead.")
 	outputVerboseMax := flag.Int("n", 0, "Maximum number of items to return in output.")
 	transformation := flag.String("t", "", "Transformation to apply to input.")
 	replacementMask := flag.String("rm", "uldsbt", "Replacement mask for transformations if applicable.")
 	jsonOutput := flag.String("o", "", "Output to JSON file in addition to stdout. Accepts file names and paths.")
+	normalizeMode := flag.String("normalize", "", "Normalize the counts of each input source before weighting so sources of any size contribute equally. [count, rank].")
+	outputFormat := flag.String("oformat", "", "Output each item as a JSON Lines or CSV record with its frequency instead of plain lines. Can be imported again with -f. [jsonl, csv].")
+	outputFile := flag.String("ofile", "", "Output -oformat records to a file in addition to stdout. Accepts file names and paths.")
+	outputMetadata := flag.String("ometa", "", "Comma separated metadata to add to -oformat records. The mask uses the -rm replacement mask. [mask, length, category, source, hashtype, all].")
+	provenanceOutput := flag.String("provenance", "", "Output the sources that produced each item to a JSON file, or a CSV file if the name ends in .csv. Accepts file names and paths.")
+	hcstatOutput := flag.String("hcstat2", "", "Output Markov statistics to a hashcat .hcstat2 file in addition to stdout. Accepts file names and paths.")
 	bypassMap := flag.Bool("b", false, "Bypass map creation and use stdout as primary output. Disables some options.")
 	debugMode := flag.Int("d", 0, "Enable debug mode with verbosity levels [0-2].")
-	URLParsingMode := flag.Int("p", 0, "Change parsing mode for URL input. [0 = Strict, 1 = Permissive, 2 = Maximum].")
+	URLParsingMode := flag.Int("p", 0, "Change parsing mode for URL and document input. [0 = Strict, 1 = Permissive, 2 = Maximum].")
 	ignoreCase := flag.Bool("ic", false, "Ignore case when processing output and converts all output to lowercase.")
+	potfileInput := flag.Bool("pot", false, "Parse -f files and standard input as hashcat potfiles or John the Ripper pot files and keep only the plaintext.")
+	potfileHashType := flag.Bool("pothash", false, "Add the detected hash types of -pot plaintext to -oformat records. Same as adding hashtype to -ometa.")
+	headerRow := flag.Bool("csvheader", false, "Skip the header row of CSV and TSV files when a column is selected by number.")
+	columnField := flag.String("field", "", "Column number or field name to extract from CSV, TSV, or JSON Lines -f files. Files can also use the file#column format.")
+	inputEncoding := flag.String("encoding", "", "Encoding of input files and standard input such as latin1, windows-1252, utf-16le, or shift_jis. Use auto to detect the encoding.")
+	outputEncoding := flag.String("outencoding", "", "Encoding of output such as latin1 or shift_jis. Unsupported characters are written as $HEX[...]. Use hex to only convert invalid UTF-8. UTF-16 is not supported.")
+	punctuation := flag.String("punct", utils.DefaultPunctuation, "Characters that split sentences into phrases for URL and document input with the -p 1 and -p 2 parsing modes.")
+	htmlSourceList := flag.String("html", "text", "Comma separated HTML sources to extract for URL and document input. [text, title, meta, attribute, link, email, username, all].")
+	crawlDepth := flag.Int("crawl", 0, "Crawl same-site links from -u URLs up to a depth. [0 = Disabled].")
+	crawlMaxPages := flag.Int("crawlmax", 100, "Maximum number of pages to fetch when crawling. [0 = Unlimited].")
+	crawlSitemap := flag.Bool("sitemap", false, "Discover pages to crawl from sitemap.xml and the sitemaps listed in robots.txt.")
+	ignoreRobots := flag.Bool("ignorerobots", false, "Ignore robots.txt rules when crawling.")
+	httpProxy := flag.String("proxy", "", "Proxy URL for URL input such as http://127.0.0.1:8080 or socks5://127.0.0.1:1080.")
+	httpCookieFile := flag.String("cookies", "", "Read a Netscape cookies.txt file for URL input.")
+	httpInsecure := flag.Bool("insecure", false, "Skip TLS certificate verification for URL input.")
+	httpTimeout := flag.Int("timeout", utils.DefaultHTTPTimeout, "Timeout in seconds for each URL request. [0 = No timeout].")
+	httpUserAgent := flag.String("useragent", "", "User agent for URL input instead of a random browser user agent.")
+	httpRate := flag.Float64("rate", utils.DefaultRequestsPerSecond, "Maximum requests per second to each host for URL input. [0 = Unlimited].")
+	httpConcurrency := flag.Int("concurrency", utils.DefaultConcurrency, "Maximum number of URL requests at once. [0 = Unlimited].")
+	httpCacheDir := flag.String("cache", "", "Cache URL responses in a directory and revalidate them with ETag and Last-Modified headers.")
+	httpOffline := flag.Bool("offline", false, "Only read URL responses from the -cache directory without sending requests.")
+	maxLineLength := flag.Int("maxline", utils.DefaultMaxLineLength, "Maximum line length in bytes when reading input. Longer lines are skipped.")
 	flag.Var(&retain, "k", "Only keep items in a file.")
 	flag.Var(&remove, "r", "Only keep items not in a file.")
-	flag.Var(&readFiles, "f", "Read additional files for input.")
+	flag.Var(&readFiles, "f", "Read additional files for input. Use file:weight to multiply the counts of a file.")
 	flag.Var(&transformationFiles, "tf", "Read additional files for transformations if applicable.")
 	flag.Var(&templateFiles, "tp", "Read a template file for multiple transformations and operations. Cannot be used with -t flag.")
 	flag.Var(&intRange, "i", "Starting index for transformations if applicable. Accepts ranges separated by '-'.")
 	flag.Var(&lenRange, "l", "Only output items of a certain length (does not adjust for rules). Accepts ranges separated by '-'.")
 	flag.Var(&wordRange, "w", "Number of words for transformations if applicable. Accepts ranges separated by '-'.")
-	flag.Var(&readURLs, "u", "Read additional URLs for input.")
+	flag.Var(&readURLs, "u", "Read additional URLs for input. Use URL:weight to multiply the counts of a URL.")
+	flag.Var(&readDocuments, "doc", "Read local HTML, PDF, DOCX, XLSX, JSON, EML, mbox, WARC, or text documents for input. Uses the -p parsing mode. Use file:weight to multiply the counts of a document.")
+	flag.Var(&crawlInclude, "crawlinclude", "Only crawl links matching a regular expression.")
+	flag.Var(&crawlExclude, "crawlexclude", "Skip crawled links matching a regular expression.")
+	flag.Var(&jsonPaths, "jsonpath", "Only extract strings under a JSONPath-like field such as $.items[*].title from JSON URL and document input.")
+	flag.Var(&httpHeaders, "header", "Add a 'Name: value' header to URL requests.")
+	flag.Var(&includeGlobs, "include", "Only read directory and archive members matching a glob pattern.")
+	flag.Var(&excludeGlobs, "exclude", "Skip directory and archive members matching a glob pattern.")
+	flag.Var(&ntdsFiles, "ntds", "Read pwdump, NTDS, or secretsdump files of user:rid:lm:nt::: lines and use cracked plaintext for input.")
+	flag.Var(&ntdsPotfiles, "ntdspot", "Read hashcat or John the Ripper potfiles to join with -ntds files.")
 	flag.Parse()
 
 	// Bypass map creation if requested
@@ -115,6 +168,18 @@ func main() {
 		fmt.Fprintf(os.Stderr, "[*] Bypassing map creation and using standard output as primary output. Options are disabled. This does not bypass the initial input memory usage.\n")
 	}
 
+	// Provenance tracking requires the output map
+	if *provenanceOutput != "" && *bypassMap {
+		fmt.Fprintf(os.Stderr, "[!] Provenance tracking cannot be used with bypass mode.\n")
+		return
+	}
+
+	// Structured output requires the output map
+	if *outputFormat != "" && *bypassMap {
+		fmt.Fprintf(os.Stderr, "[!] Structured output cannot be used with bypass mode.\n")
+		return
+	}
+
 	// Print debug information if requested
 	if *debugMode > 0 {
 		fmt.Fprintf(os.Stderr, "[*] Debug mode enabled with verbosity level %d.\n", *debugMode)
@@ -127,64 +192,369 @@ func main() {
 	var readFilesMap map[string]int
 	var transformationFilesMap map[string]int
 	doneLoad := make(chan bool)
+
+	// Parse the weights of file, document, and URL sources
+	sourceWeights := make(map[string]float64)
+	for _, sources := range []models.FileArgumentFlag{readFiles, readDocuments, readURLs} {
+		for i, source := range sources {
+			name, weight, err := utils.SplitSourceWeight(source)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error parsing source weight: %s.\n", err)
+				return
+			}
+			sources[i] = name
+			if weight != 1 {
+				sourceWeights[name] = weight
+			}
+		}
+	}
+
+	if *normalizeMode != "" && *normalizeMode != "count" && *normalizeMode != "rank" {
+		fmt.Fprintf(os.Stderr, "[!] Error parsing normalization mode: unknown normalization mode %s.\n", *normalizeMode)
+		return
+	}
+
+	// Parse the structured output format and metadata if provided
+	if *outputFormat != "" && !slices.Contains(format.OutputFormats, *outputFormat) {
+		fmt.Fprintf(os.Stderr, "[!] Error parsing output format: unknown output format %s.\n", *outputFormat)
+		return
+	} else if *outputFormat == "" && (*outputFile != "" || *outputMetadata != "") {
+		fmt.Fprintf(os.Stderr, "[!] Output files and metadata require an output format with -oformat.\n")
+		return
+	}
+
+	// Check the output encoding before any input is loaded
+	if *outputEncoding != "" {
+		if _, err := format.GetOutputEncoder(*outputEncoding); err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error parsing output encoding: %s.\n", err)
+			return
+		}
+	}
+
+	outputFields, err := format.ParseOutputFields(*outputMetadata)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Error parsing output metadata: %s.\n", err)
+		return
+	}
+
+	// Hash types are kept as record metadata so plaintext is not changed
+	if *potfileHashType && (!*potfileInput || *outputFormat == "") {
+		fmt.Fprintf(os.Stderr, "[!] Hash types from -pothash require -pot and an output format with -oformat.\n")
+		return
+	} else if *potfileHashType && !slices.Contains(outputFields, "hashtype") {
+		outputFields = append(outputFields, "hashtype")
+	}
+
+	// Record the hash types of potfile plaintext for the hashtype metadata
+	var hashTypes map[string][]string
+	if *potfileInput && slices.Contains(outputFields, "hashtype") {
+		hashTypes = make(map[string][]string)
+	}
+
+	// Track the sources of each item for the provenance file or source metadata
+	trackSources := *provenanceOutput != "" || slices.Contains(outputFields, "source")
+
+	// Load each source separately if sources are weighted, normalized, or
+	// tracked and record the sources of each item if sources are tracked
+	perSource := len(sourceWeights) > 0 || *normalizeMode != "" || trackSources
+	itemSources := make(map[string][]string)
+	trackedSources := make(map[string]bool)
+
+	// Scale every source so the smallest weight is 1 and no item is rounded
+	// away while the weights keep their ratios
+	weightScale := utils.GetWeightScale(slices.Collect(maps.Values(sourceWeights)))
+	weightSource := func(name string, items map[string]int) map[string]int {
+		weight, ok := sourceWeights[name]
+		if !ok {
+			weight = 1
+		}
+		if weight*weightScale != 1 {
+			items = utils.WeightMap(items, weight*weightScale)
+		}
+		return items
+	}
+
+	// Keep sources to normalize until all are loaded so they share a total
+	type normalizedSource struct {
+		name  string
+		items map[string]int
+	}
+	var normalizedSources []normalizedSource
+	loadSource := func(name string, items map[string]int) map[string]int {
+		if trackSources {
+			trackedSources[name] = true
+			utils.AddProvenance(itemSources, items, name)
+		}
+		if *normalizeMode != "" {
+			normalizedSources = append(normalizedSources, normalizedSource{name, items})
+			return nil
+		}
+		return weightSource(name, items)
+	}
+
+	// Parse potfile input as it is loaded so sources hold the plaintext
+	parsePotfile := func(items map[string]int) map[string]int {
+		if *potfileInput {
+			return utils.ParsePotfileMap(items, hashTypes)
+		}
+		return items
+	}
+
 	go utils.TrackLoadTime(doneLoad, "Load")
 
 	// Read files if provided
-	if retain != nil || remove != nil || readFiles != nil || transformationFiles != nil {
+	if retain != nil || remove != nil || readFiles != nil || transformationFiles != nil || ntdsFiles != nil {
 		fmt.Fprintf(os.Stderr, "[*] Reading files for input.\n")
 	}
+	if *potfileInput {
+		fmt.Fprintf(os.Stderr, "[*] Parsing input as potfiles.\n")
+	}
 
 	if retain != nil {
-		retainMap = utils.ReadFilesToMap(fs, retain)
+		retainMap = utils.ReadFilesToMap(fs, retain, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs)
 	}
 	if remove != nil {
-		removeMap = utils.ReadFilesToMap(fs, remove)
+		removeMap = utils.ReadFilesToMap(fs, remove, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs)
 	}
 	if readFiles != nil {
-		readFilesMap = utils.ReadFilesToMap(fs, readFiles)
+		if *columnField != "" {
+			for i, filename := range readFiles {
+				if _, selector := utils.SplitColumnSelector(fs, filename); selector == "" {
+					readFiles[i] = filename + "#" + *columnField
+					if weight, ok := sourceWeights[filename]; ok {
+						sourceWeights[readFiles[i]] = weight
+					}
+				}
+			}
+		}
+		if perSource {
+			for _, filename := range readFiles {
+				fileMap := parsePotfile(utils.ReadFilesToMap(fs, []string{filename}, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs))
+				readFilesMap = utils.CombineMaps(readFilesMap, loadSource(filename, fileMap))
+			}
+		} else {
+			readFilesMap = parsePotfile(utils.ReadFilesToMap(fs, readFiles, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs))
+		}
 	}
 	if transformationFiles != nil {
-		transformationFilesMap = utils.ReadFilesToMap(fs, transformationFiles)
+		transformationFilesMap = utils.ReadFilesToMap(fs, transformationFiles, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs)
 	}
 
 	transformationTemplateArray := utils.ReadJSONToArray(fs, templateFiles)
-	readURLsMap, err := utils.ReadURLsToMap(readURLs, *URLParsingMode, *debugMode)
+
+	// Use HTTP client settings from templates unless set by flags
+	timeoutSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "timeout" {
+			timeoutSet = true
+		}
+	})
+	httpOptions := models.HTTPOptions{
+		Proxy:             *httpProxy,
+		Headers:           httpHeaders,
+		CookieFile:        *httpCookieFile,
+		Insecure:          *httpInsecure,
+		Timeout:           *httpTimeout,
+		UserAgent:         *httpUserAgent,
+		RequestsPerSecond: *httpRate,
+		Concurrency:       *httpConcurrency,
+		CacheDir:          *httpCacheDir,
+		Offline:           *httpOffline,
+	}
+	for _, template := range transformationTemplateArray {
+		if httpOptions.Proxy == "" {
+			httpOptions.Proxy = template.Proxy
+		}
+		if httpOptions.CookieFile == "" {
+			httpOptions.CookieFile = template.CookieFile
+		}
+		if httpOptions.UserAgent == "" {
+			httpOptions.UserAgent = template.UserAgent
+		}
+		if !timeoutSet && template.Timeout > 0 {
+			httpOptions.Timeout = template.Timeout
+		}
+		httpOptions.Insecure = httpOptions.Insecure || template.Insecure
+		httpOptions.Headers = append(httpOptions.Headers, template.Headers...)
+	}
+
+	if err := utils.ConfigureHTTPClient(fs, httpOptions); err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Error configuring HTTP client: %s.\n", err)
+		return
+	}
+
+	htmlSources, err := document.ParseHTMLSources(*htmlSourceList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Error parsing HTML sources: %s.\n", err)
+		return
+	}
+
+	for _, path := range jsonPaths {
+		if _, err := document.ParseJSONPath(path); err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error parsing JSON paths: %s.\n", err)
+			return
+		}
+	}
+	extractOptions := models.ExtractOptions{
+		HTMLSources: htmlSources,
+		JSONPaths:   jsonPaths,
+		Punctuation: *punctuation,
+	}
+
+	crawlOptions := models.CrawlOptions{
+		Depth:        *crawlDepth,
+		MaxPages:     *crawlMaxPages,
+		Include:      crawlInclude,
+		Exclude:      crawlExclude,
+		Sitemap:      *crawlSitemap,
+		IgnoreRobots: *ignoreRobots,
+	}
+	// Keep the words of each URL if URL sources are weighted, normalized, or
+	// tracked. Every URL shares one crawl so the output is not changed.
+	var urlMaps map[string]map[string]int
+	if readURLs != nil && (*normalizeMode != "" || trackSources) {
+		urlMaps = make(map[string]map[string]int)
+	}
+	for _, source := range readURLs {
+		weight, ok := sourceWeights[source]
+		if !ok {
+			continue
+		}
+		if urlMaps == nil {
+			urlMaps = make(map[string]map[string]int)
+		}
+
+		// Apply the weight of a file of URLs to every URL in the file
+		if !utils.IsValidURL(source) {
+			collected, _ := utils.CollectURLs([]string{source}, includeGlobs, excludeGlobs)
+			for _, iURL := range collected {
+				sourceWeights[iURL] = weight
+			}
+		}
+	}
+
+	readURLsMap, err := utils.ReadURLsToMap(readURLs, *URLParsingMode, *debugMode, includeGlobs, excludeGlobs, crawlOptions, extractOptions, urlMaps)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[!] Error reading URLs: %s.\n", err)
 		return
 	}
+	if urlMaps != nil {
+		readURLsMap = nil
+		for _, iURL := range slices.Sorted(maps.Keys(urlMaps)) {
+			readURLsMap = utils.CombineMaps(readURLsMap, loadSource(iURL, urlMaps[iURL]))
+		}
+	} else if perSource && len(readURLsMap) > 0 {
+		readURLsMap = loadSource(strings.Join(readURLs, ","), readURLsMap)
+	}
+
+	var readDocumentsMap map[string]int
+	if perSource {
+		for _, filename := range readDocuments {
+			documentMap, err := utils.ReadDocumentsToMap(fs, []string{filename}, *URLParsingMode, *debugMode, includeGlobs, excludeGlobs, extractOptions)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error reading documents: %s.\n", err)
+				return
+			}
+			readDocumentsMap = utils.CombineMaps(readDocumentsMap, loadSource(filename, documentMap))
+		}
+	} else {
+		readDocumentsMap, err = utils.ReadDocumentsToMap(fs, readDocuments, *URLParsingMode, *debugMode, includeGlobs, excludeGlobs, extractOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error reading documents: %s.\n", err)
+			return
+		}
+	}
 
 	// Read from stdin if provided
 	stat, _ := os.Stdin.Stat()
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		primaryMap, err = utils.LoadStdinToMap(bufio.NewScanner(os.Stdin))
+		stdinReader, err := utils.GetDecompressedReader(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error decompressing standard input: %s.\n", err)
+			return
+		}
+
+		stdinReader, err = utils.GetDecodedReader(stdinReader, *inputEncoding)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error decoding standard input: %s.\n", err)
+			return
+		}
+
+		primaryMap, err = utils.LoadStdinToMap(utils.NewLineScanner(stdinReader, *maxLineLength))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "[!] Error reading from standard input: %s.\n", err)
 			return
 		}
+		primaryMap = parsePotfile(primaryMap)
+		if perSource {
+			primaryMap = loadSource("stdin", primaryMap)
+		}
+	}
+
+	// Parse credential dumps and join cracked potfiles if provided
+	var credentials []models.Credential
+	if ntdsFiles != nil {
+		fmt.Fprintf(os.Stderr, "[*] Parsing credential dump files.\n")
+		credentials = utils.ParseCredentialMap(utils.ReadFilesToMap(fs, ntdsFiles, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs))
+		if ntdsPotfiles != nil {
+			credentials = utils.JoinCredentialsWithPotfile(credentials, utils.ReadFilesToMap(fs, ntdsPotfiles, *maxLineLength, *inputEncoding, *headerRow, includeGlobs, excludeGlobs))
+		}
+		credentialsMap := utils.CredentialsToMap(credentials)
+		if perSource {
+			credentialsMap = loadSource(strings.Join(ntdsFiles, ","), credentialsMap)
+		}
+		readFilesMap = utils.CombineMaps(readFilesMap, credentialsMap)
+	}
+
+	// Normalize the sources to a common total once every source is loaded
+	if len(normalizedSources) > 0 {
+		var sources []map[string]int
+		for _, source := range normalizedSources {
+			sources = append(sources, source.items)
+		}
+		total := utils.GetNormalizedTotal(sources, *normalizeMode)
+		sources = nil
+		for i, source := range normalizedSources {
+			items, _ := utils.NormalizeMap(source.items, *normalizeMode, total)
+			readFilesMap = utils.CombineMaps(readFilesMap, weightSource(source.name, items))
+			normalizedSources[i].items = nil
+		}
 	}
 
 	// Combine stdin with any additional files
-	if len(primaryMap) == 0 && len(readFilesMap) == 0 && len(readURLsMap) == 0 {
+	if len(primaryMap) == 0 && len(readFilesMap) == 0 && len(readURLsMap) == 0 && len(readDocumentsMap) == 0 {
 		fmt.Fprintf(os.Stderr, "[!] No input provided. Exiting.\n")
 		return
 	} else if len(primaryMap) == 0 {
-		primaryMap = utils.CombineMaps(readFilesMap, readURLsMap)
+		primaryMap = utils.CombineMaps(readFilesMap, readURLsMap, readDocumentsMap)
 	} else {
-		primaryMap = utils.CombineMaps(primaryMap, readFilesMap, readURLsMap)
+		primaryMap = utils.CombineMaps(primaryMap, readFilesMap, readURLsMap, readDocumentsMap)
 	}
 
 	doneLoad <- true
 	close(doneLoad)
+	if skipped := utils.GetSkippedLineCount(); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "[!] Skipped %d lines longer than %d bytes. Use -maxline to raise the limit.\n", skipped, *maxLineLength)
+	}
 	fmt.Fprintf(os.Stderr, "[*] All input loaded.\n")
 	fmt.Fprintf(os.Stderr, "[*] Starting Processing.\n")
 
 	doneProcess := make(chan bool)
 	go utils.TrackLoadTime(doneProcess, "Processing")
 
+	// Track the sources of each item through the transformations if provided
+	provenance := itemSources
+	if trackSources {
+		fmt.Fprintf(os.Stderr, "[*] Tracking the sources of %d inputs.\n", len(trackedSources))
+	}
+
 	// Apply transformation if provided
 	if *transformation != "" && templateFiles == nil {
-		primaryMap = transform.TransformationController(primaryMap, *transformation, intRange.Start, intRange.End, *verbose, *replacementMask, transformationFilesMap, *bypassMap, *debugMode, wordRange.Start, wordRange.End)
+		if trackSources {
+			primaryMap, provenance = transform.TransformationSourceController(primaryMap, itemSources, *transformation, intRange.Start, intRange.End, *verbose, *replacementMask, transformationFilesMap, *bypassMap, *debugMode, wordRange.Start, wordRange.End)
+		} else {
+			primaryMap = transform.TransformationController(primaryMap, *transformation, intRange.Start, intRange.End, *verbose, *replacementMask, transformationFilesMap, *bypassMap, *debugMode, wordRange.Start, wordRange.End)
+		}
 	} else if templateFiles != nil && *transformation == "" {
 		fmt.Fprintf(os.Stderr, "[*] Using template files for multiple transformations.\n")
 
@@ -194,12 +564,29 @@ func main() {
 			temporaryMap[k] = v
 		}
 
-		// Apply transformations from template files
+		// Apply transformations from template files and credit the sources
+		// of each item to the step that produced it
+		if trackSources {
+			provenance = make(map[string][]string)
+		}
 		for i, template := range transformationTemplateArray {
+			var stepMap map[string]int
+			if trackSources {
+				var stepSources map[string][]string
+				stepMap, stepSources = transform.TransformationSourceController(primaryMap, itemSources, template.TransformationMode, template.StartIndex, template.EndIndex, template.Verbose, template.ReplacementMask, transformationFilesMap, template.Bypass, *debugMode, template.WordRangeStart, template.WordRangeEnd)
+				for item, sources := range stepSources {
+					for _, source := range sources {
+						provenance[item] = append(provenance[item], fmt.Sprintf("%s (template step %d: %s)", source, i+1, template.TransformationMode))
+					}
+				}
+			} else {
+				stepMap = transform.TransformationController(primaryMap, template.TransformationMode, template.StartIndex, template.EndIndex, template.Verbose, template.ReplacementMask, transformationFilesMap, template.Bypass, *debugMode, template.WordRangeStart, template.WordRangeEnd)
+			}
+
 			if i == 0 {
-				temporaryMap = transform.TransformationController(primaryMap, template.TransformationMode, template.StartIndex, template.EndIndex, template.Verbose, template.ReplacementMask, transformationFilesMap, template.Bypass, *debugMode, template.WordRangeStart, template.WordRangeEnd)
+				temporaryMap = stepMap
 			} else {
-				temporaryMap = utils.CombineMaps(temporaryMap, transform.TransformationController(primaryMap, template.TransformationMode, template.StartIndex, template.EndIndex, template.Verbose, template.ReplacementMask, transformationFilesMap, template.Bypass, *debugMode, template.WordRangeStart, template.WordRangeEnd))
+				temporaryMap = utils.CombineMaps(temporaryMap, stepMap)
 			}
 		}
 		primaryMap = temporaryMap
@@ -220,6 +607,8 @@ func main() {
 	// Ignore case if provided
 	if *ignoreCase {
 		primaryMap = format.CreateIgnoreCaseMap(primaryMap)
+		provenance = format.CreateIgnoreCaseProvenance(provenance)
+		hashTypes = format.CreateIgnoreCaseProvenance(hashTypes)
 	}
 
 	// Print remove frequency if provided
@@ -242,6 +631,16 @@ func main() {
 		primaryMap = format.RemoveLengthRange(primaryMap, lenRange.Start, lenRange.End)
 	}
 
+	// Print minimum entropy if provided
+	if *minimumEntropy > 0 {
+		fmt.Fprintf(os.Stderr, "[*] Removing items with entropy less than %.2f bits.\n", *minimumEntropy)
+	}
+
+	// Remove items under minimum entropy if provided
+	if *minimumEntropy > 0 {
+		primaryMap = format.RemoveMinimumEntropy(primaryMap, *minimumEntropy, transformationFilesMap)
+	}
+
 	// Print retained and removed items if provided
 	if len(retainMap) > 0 || len(removeMap) > 0 {
 		fmt.Fprintf(os.Stderr, "[*] Retain/remove flags provided. Retaining %d and removing %d items.\n", len(retainMap), len(removeMap))
@@ -263,6 +662,17 @@ func main() {
 
 	fmt.Fprintf(os.Stderr, "[*] Task complete with %d unique results.\n", len(primaryMap))
 
+	// Encode output if provided
+	outputMap := primaryMap
+	if *outputEncoding != "" {
+		fmt.Fprintf(os.Stderr, "[*] Encoding output as %s.\n", *outputEncoding)
+		outputMap, err = format.EncodeOutputMap(primaryMap, *outputEncoding)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error encoding output: %s.\n", err)
+			return
+		}
+	}
+
 	// Print in markdown if provided
 	if *markDownOutput {
 		command := "ptt "
@@ -270,16 +680,36 @@ func main() {
 			command += arg + " "
 		}
 
-		format.PrintArrayToMarkdown(primaryMap, command)
+		format.PrintArrayToMarkdown(outputMap, command)
 	}
 
 	// Print output to stdout
-	if *verbose3 && !*markDownOutput {
+	outputOptions := models.OutputOptions{
+		Format:          *outputFormat,
+		Fields:          outputFields,
+		ReplacementMask: *replacementMask,
+		Encoding:        *outputEncoding,
+		Provenance:      provenance,
+		HashTypes:       hashTypes,
+	}
+	if *outputFormat != "" && *outputFile == "" && !*markDownOutput {
+		err = format.WriteOutputRecords(os.Stdout, primaryMap, outputOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error writing output records: %s.\n", err)
+			return
+		}
+	} else if *verbose3 && !*markDownOutput {
 		format.PrintStatsToSTDOUT(primaryMap, *verbose3, *outputVerboseMax)
 	} else if *verbose2 && !*markDownOutput {
 		format.PrintStatsToSTDOUT(primaryMap, *verbose3, *outputVerboseMax)
 	} else if !*markDownOutput {
-		format.PrintArrayToSTDOUT(primaryMap, *verbose)
+		format.PrintArrayToSTDOUT(outputMap, *verbose)
+	}
+
+	// Print credential statistics if provided
+	if (*verbose2 || *verbose3) && !*markDownOutput && len(credentials) > 0 {
+		fmt.Println("--------------------------------------------------")
+		fmt.Println(format.CreateCredentialStats(credentials, *outputVerboseMax))
 	}
 
 	// Print output location if provided
@@ -295,4 +725,46 @@ func main() {
 			return
 		}
 	}
+
+	// Print structured output location if provided
+	if *outputFile != "" {
+		fmt.Fprintf(os.Stderr, "[*] Saving output records to %s file: %s.\n", *outputFormat, *outputFile)
+	}
+
+	// Save structured output if provided
+	if *outputFile != "" {
+		err = format.SaveOutputRecords(*outputFile, primaryMap, outputOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error saving output records: %s.\n", err)
+			return
+		}
+	}
+
+	// Print provenance output location if provided
+	if *provenanceOutput != "" {
+		fmt.Fprintf(os.Stderr, "[*] Saving item sources to file: %s.\n", *provenanceOutput)
+	}
+
+	// Save item sources if provided
+	if *provenanceOutput != "" {
+		err = format.SaveProvenance(*provenanceOutput, primaryMap, provenance)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error saving item sources: %s.\n", err)
+			return
+		}
+	}
+
+	// Print hcstat2 output location if provided
+	if *hcstatOutput != "" {
+		fmt.Fprintf(os.Stderr, "[*] Saving Markov statistics to hcstat2 file: %s.\n", *hcstatOutput)
+	}
+
+	// Save output to hcstat2 if provided
+	if *hcstatOutput != "" {
+		err = format.SaveMarkovToHcstat2(*hcstatOutput, primaryMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error saving output to hcstat2: %s.\n", err)
+			return
+		}
+	}
 }
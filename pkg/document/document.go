@@ -0,0 +1,1527 @@
+// Package document contains the logic to extract text from local documents
+// such as HTML, DOCX, XLSX, PDF, email, and WARC files
+package document
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/jakewnuk/ptt/pkg/models"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ----------------------------------------------------------------------------
+// Extraction Functions
+// ----------------------------------------------------------------------------
+
+// IsDocumentFile checks if a file is a document that text can be extracted
+// from by its extension
+//
+// Args:
+//
+//	name (string): The name of the file
+//
+// Returns:
+//
+//	bool: True if the file is a supported document
+func IsDocumentFile(name string) bool {
+	return GetDocumentType(name, nil) != "text"
+}
+
+// GetDocumentType returns the type of a document as "html", "docx", "xlsx",
+// "pdf", "eml", "mbox", "warc", or "text" by its extension or the first bytes
+// of its data
+//
+// Args:
+//
+//	name (string): The name of the file
+//	data ([]byte): The contents of the file if available
+//
+// Returns:
+//
+//	string: The type of the document
+func GetDocumentType(name string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm", ".xhtml":
+		return "html"
+	case ".docx", ".docm", ".dotx":
+		return "docx"
+	case ".xlsx", ".xlsm":
+		return "xlsx"
+	case ".pdf":
+		return "pdf"
+	case ".eml":
+		return "eml"
+	case ".mbox", ".mbx":
+		return "mbox"
+	case ".warc":
+		return "warc"
+	case ".json", ".jsonl", ".ndjson":
+		return "json"
+	}
+
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "pdf"
+	}
+
+	if bytes.HasPrefix(data, []byte("WARC/")) {
+		return "warc"
+	}
+
+	if mboxPattern.Match(data) {
+		return "mbox"
+	}
+
+	return "text"
+}
+
+// ExtractDocumentText extracts lines of text from a document based on its
+// type. Documents that are not recognized are split into lines. WARC files
+// are not extracted here since their responses are read record by record
+// with WalkWARC and parsed like live URL responses.
+//
+// Args:
+//
+//	name (string): The name of the file
+//	data ([]byte): The contents of the file
+//	options (models.ExtractOptions): The HTML sources and JSON paths to
+//	extract
+//
+// Returns:
+//
+//	[]string: The lines of text in the document
+//	[]string: The email and username values to keep whole
+//	error: An error if one occurred
+func ExtractDocumentText(name string, data []byte, options models.ExtractOptions) ([]string, []string, error) {
+	var lines []string
+	var err error
+
+	switch GetDocumentType(name, data) {
+	case "html":
+		return ExtractHTMLSources(string(data), options.HTMLSources)
+	case "json":
+		lines, err = ExtractJSONText(data, options.JSONPaths)
+	case "docx":
+		lines, err = ExtractDOCXText(data)
+	case "xlsx":
+		lines, err = ExtractXLSXText(data)
+	case "pdf":
+		lines, err = ExtractPDFText(data)
+	case "eml":
+		lines, err = ExtractEmailText(data)
+	case "mbox":
+		lines, err = ExtractMboxText(data)
+	case "warc":
+		err = fmt.Errorf("WARC files must be read with WalkWARC")
+	default:
+		lines = strings.Split(string(data), "\n")
+	}
+
+	return lines, nil, err
+}
+
+// mboxPattern matches the "From " separator line and first header of an mbox
+// file
+var mboxPattern = regexp.MustCompile(`^From \S+ [^\r\n]*\r?\n[A-Za-z0-9-]+:`)
+
+// HTMLSources are the sources of text that can be extracted from HTML
+// documents. Emails and usernames are values that should not be split into
+// sentences.
+var HTMLSources = []string{"text", "title", "meta", "attribute", "link", "email", "username"}
+
+// htmlValueSources are the HTML sources kept as whole values
+var htmlValueSources = map[string]bool{"email": true, "username": true}
+
+// htmlTextSources are the HTML sources whose text nodes are not emitted as
+// "text" and are instead included with it when they are not selected
+var htmlTextSources = map[string]bool{"title": true, "link": true}
+
+// htmlMetaNames are the meta names and properties harvested from HTML
+var htmlMetaNames = map[string]bool{
+	"description":         true,
+	"keywords":            true,
+	"author":              true,
+	"og:title":            true,
+	"og:description":      true,
+	"og:site_name":        true,
+	"twitter:title":       true,
+	"twitter:description": true,
+}
+
+// emailPattern matches email addresses and usernamePattern matches @handles
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+var usernamePattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@])@([A-Za-z0-9_]{2,30})\b`)
+
+// htmlTagPattern matches an opening or closing HTML tag
+var htmlTagPattern = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9]*(\s[^<>]*)?/?>`)
+
+// HTMLField is text extracted from an HTML document along with the source it
+// was found in
+type HTMLField struct {
+	Source string
+	Text   string
+}
+
+// ParseHTMLSources parses a comma separated list of HTML sources. The "all"
+// source selects every source.
+//
+// Args:
+//
+//	list (string): The comma separated sources
+//
+// Returns:
+//
+//	[]string: The selected sources
+//	error: An error if a source is not known
+func ParseHTMLSources(list string) ([]string, error) {
+	var sources []string
+	for _, source := range strings.Split(list, ",") {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if source == "" {
+			continue
+		}
+		if source == "all" {
+			return HTMLSources, nil
+		}
+
+		known := false
+		for _, name := range HTMLSources {
+			known = known || name == source
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown HTML source %s", source)
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// ExtractHTMLText extracts the text nodes from an HTML document. Script and
+// style contents are skipped.
+//
+// Args:
+//
+//	text (string): The HTML document
+//
+// Returns:
+//
+//	[]string: The text nodes in the document
+//	error: An error if one occurred
+func ExtractHTMLText(text string) ([]string, error) {
+	lines, _, err := ExtractHTMLSources(text, nil)
+	return lines, err
+}
+
+// ExtractHTMLSources extracts the text of the selected sources from an HTML
+// document. Only text nodes are extracted when no sources are given. The
+// title and link text are part of the "text" source unless they are selected
+// themselves so each is extracted once.
+//
+// Args:
+//
+//	text (string): The HTML document
+//	sources ([]string): The sources to extract
+//
+// Returns:
+//
+//	[]string: The lines of text to parse into sentences
+//	[]string: The email and username values to keep whole
+//	error: An error if one occurred
+func ExtractHTMLSources(text string, sources []string) ([]string, []string, error) {
+	fields, err := ExtractHTMLFields(text)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(sources) == 0 {
+		sources = []string{"text"}
+	}
+	selected := make(map[string]bool)
+	for _, source := range sources {
+		selected[source] = true
+	}
+
+	var lines, values []string
+	for _, field := range fields {
+		if !selected[field.Source] && !(selected["text"] && htmlTextSources[field.Source]) {
+			continue
+		}
+		if htmlValueSources[field.Source] {
+			values = append(values, field.Text)
+		} else {
+			lines = append(lines, field.Text)
+		}
+	}
+
+	return lines, values, nil
+}
+
+// ExtractHTMLFields extracts text from an HTML document tagged by source:
+// text nodes, the title, meta descriptions and keywords, alt and title
+// attributes, link text, and the emails and usernames found in them. Script
+// and style contents are skipped. Text nodes inside the title and links are
+// only emitted as those sources and empty titles and links are skipped.
+//
+// Args:
+//
+//	text (string): The HTML document
+//
+// Returns:
+//
+//	[]HTMLField: The extracted text in document order
+//	error: An error if one occurred
+func ExtractHTMLFields(text string) ([]HTMLField, error) {
+	var fields []HTMLField
+	text = html.UnescapeString(text)
+
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+
+	addValues := func(text string) {
+		for _, email := range emailPattern.FindAllString(text, -1) {
+			fields = append(fields, HTMLField{"email", email})
+			fields = append(fields, HTMLField{"username", email[:strings.Index(email, "@")]})
+		}
+		for _, match := range usernamePattern.FindAllStringSubmatch(text, -1) {
+			fields = append(fields, HTMLField{"username", match[1]})
+		}
+	}
+
+	// Traverse the HTML tree and extract the text
+	var f func(*html.Node, bool)
+	f = func(n *html.Node, claimed bool) {
+		switch n.Type {
+		case html.TextNode:
+			if !claimed {
+				fields = append(fields, HTMLField{"text", n.Data})
+			}
+			addValues(n.Data)
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style":
+				return
+			case "title":
+				claimed = true
+				if title := strings.TrimSpace(getHTMLNodeText(n)); title != "" {
+					fields = append(fields, HTMLField{"title", title})
+				}
+			case "a":
+				claimed = true
+				if link := strings.Join(strings.Fields(getHTMLNodeText(n)), " "); link != "" {
+					fields = append(fields, HTMLField{"link", link})
+				}
+			case "meta":
+				name, content := "", ""
+				for _, a := range n.Attr {
+					switch a.Key {
+					case "name", "property":
+						name = strings.ToLower(a.Val)
+					case "content":
+						content = a.Val
+					}
+				}
+				if htmlMetaNames[name] {
+					if name == "keywords" {
+						for _, keyword := range strings.Split(content, ",") {
+							if keyword = strings.TrimSpace(keyword); keyword != "" {
+								fields = append(fields, HTMLField{"meta", keyword})
+							}
+						}
+					} else if content != "" {
+						fields = append(fields, HTMLField{"meta", content})
+					}
+				}
+			}
+
+			for _, a := range n.Attr {
+				switch a.Key {
+				case "alt", "title":
+					if a.Val != "" {
+						fields = append(fields, HTMLField{"attribute", a.Val})
+					}
+				case "href":
+					if strings.HasPrefix(strings.ToLower(a.Val), "mailto:") {
+						addValues(a.Val)
+					}
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c, claimed)
+		}
+	}
+	f(doc, false)
+
+	return fields, nil
+}
+
+// getHTMLNodeText returns the text of the text nodes below an HTML node
+func getHTMLNodeText(n *html.Node) string {
+	var text strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+	return text.String()
+}
+
+// ExtractHTMLLinks extracts the absolute HTTP and HTTPS links from an HTML
+// document. Relative links are resolved against the base URL or the <base>
+// element of the document and fragments are removed.
+//
+// Args:
+//
+//	text (string): The HTML document
+//	base (*url.URL): The URL the document was fetched from
+//
+// Returns:
+//
+//	[]string: The unique links in the order they appear
+func ExtractHTMLLinks(text string, base *url.URL) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil
+	}
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attribute := ""
+			switch n.Data {
+			case "a", "area":
+				attribute = "href"
+			case "frame", "iframe":
+				attribute = "src"
+			case "base":
+				for _, a := range n.Attr {
+					if a.Key == "href" {
+						if resolved, err := base.Parse(strings.TrimSpace(a.Val)); err == nil {
+							base = resolved
+						}
+					}
+				}
+			}
+
+			for _, a := range n.Attr {
+				if attribute == "" || a.Key != attribute {
+					continue
+				}
+				link, err := base.Parse(strings.TrimSpace(a.Val))
+				if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+					continue
+				}
+				link.Fragment = ""
+				link.RawFragment = ""
+				if !seen[link.String()] {
+					seen[link.String()] = true
+					links = append(links, link.String())
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+
+	return links
+}
+
+// ExtractSitemapLocations extracts the page and nested sitemap locations from
+// a sitemap or sitemap index
+//
+// Args:
+//
+//	data ([]byte): The contents of the sitemap
+//
+// Returns:
+//
+//	[]string: The locations of pages in the sitemap
+//	[]string: The locations of nested sitemaps in a sitemap index
+func ExtractSitemapLocations(data []byte) ([]string, []string) {
+	var pages, sitemaps []string
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	parent := ""
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch element.Name.Local {
+		case "url", "sitemap":
+			parent = element.Name.Local
+		case "loc":
+			var location string
+			if err := decoder.DecodeElement(&location, &element); err != nil {
+				continue
+			}
+			location = strings.TrimSpace(location)
+			if parent == "sitemap" {
+				sitemaps = append(sitemaps, location)
+			} else {
+				pages = append(pages, location)
+			}
+		}
+	}
+
+	return pages, sitemaps
+}
+
+// ParseJSONPath parses a JSONPath-like expression into path segments. Keys
+// are separated by dots or given in brackets, "*" selects every member or
+// element, "[n]" selects an array index, and ".." selects the following
+// segment at any depth. The leading "$" is optional.
+//
+// Args:
+//
+//	path (string): The path expression such as $.items[*].title
+//
+// Returns:
+//
+//	[]string: The path segments with ".." for recursive descent
+//	error: An error if the path is not valid
+func ParseJSONPath(path string) ([]string, error) {
+	var segments []string
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segments = append(segments, "..")
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				continue
+			}
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %s", path)
+			}
+			segment := strings.TrimSpace(rest[1:end])
+			if unquoted, err := strconv.Unquote(segment); err == nil {
+				segment = unquoted
+			} else if len(segment) >= 2 && segment[0] == '\'' && segment[len(segment)-1] == '\'' {
+				segment = segment[1 : len(segment)-1]
+			} else if _, err := strconv.Atoi(segment); err != nil && segment != "*" {
+				return nil, fmt.Errorf("invalid JSON path %s", path)
+			}
+			if segment == "" {
+				return nil, fmt.Errorf("invalid JSON path %s", path)
+			}
+			segments = append(segments, segment)
+			rest = rest[end+1:]
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("invalid JSON path %s", path)
+		}
+		segments = append(segments, rest[:end])
+		rest = rest[end:]
+	}
+
+	return segments, nil
+}
+
+// ExtractJSONText extracts the string values from JSON documents such as API
+// responses. Multiple documents such as JSON lines are read in order. When
+// paths are given only the strings under the selected values are extracted.
+//
+// Args:
+//
+//	data ([]byte): The JSON documents
+//	paths ([]string): The JSONPath-like expressions to select values with
+//
+// Returns:
+//
+//	[]string: The lines of the string values
+//	error: An error if one occurred
+func ExtractJSONText(data []byte, paths []string) ([]string, error) {
+	var selectors [][]string
+	for _, path := range paths {
+		segments, err := ParseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, segments)
+	}
+
+	var lines []string
+	decoder := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	decoder.UseNumber()
+
+	for {
+		var object interface{}
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return lines, err
+		}
+
+		if len(selectors) == 0 {
+			lines = appendJSONStrings(lines, object)
+			continue
+		}
+		for _, segments := range selectors {
+			for _, value := range selectJSONValues(object, segments) {
+				lines = appendJSONStrings(lines, value)
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// selectJSONValues returns the values of a decoded JSON document that match
+// the path segments
+//
+// Args:
+//
+//	value (interface{}): The decoded JSON value
+//	segments ([]string): The path segments from ParseJSONPath
+//
+// Returns:
+//
+//	[]interface{}: The selected values
+func selectJSONValues(value interface{}, segments []string) []interface{} {
+	if len(segments) == 0 {
+		return []interface{}{value}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	var selected []interface{}
+
+	switch segment {
+	case "..":
+		selected = append(selected, selectJSONValues(value, rest)...)
+		for _, child := range getJSONChildren(value) {
+			selected = append(selected, selectJSONValues(child, segments)...)
+		}
+	case "*":
+		for _, child := range getJSONChildren(value) {
+			selected = append(selected, selectJSONValues(child, rest)...)
+		}
+	default:
+		switch node := value.(type) {
+		case map[string]interface{}:
+			if child, ok := node[segment]; ok {
+				selected = selectJSONValues(child, rest)
+			}
+		case []interface{}:
+			if index, err := strconv.Atoi(segment); err == nil {
+				if index < 0 {
+					index += len(node)
+				}
+				if index >= 0 && index < len(node) {
+					selected = selectJSONValues(node[index], rest)
+				}
+			}
+		}
+	}
+
+	return selected
+}
+
+// getJSONChildren returns the members of a JSON object sorted by key or the
+// elements of a JSON array
+//
+// Args:
+//
+//	value (interface{}): The decoded JSON value
+//
+// Returns:
+//
+//	[]interface{}: The child values
+func getJSONChildren(value interface{}) []interface{} {
+	var children []interface{}
+
+	switch node := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			children = append(children, node[key])
+		}
+	case []interface{}:
+		children = node
+	}
+
+	return children
+}
+
+// appendJSONStrings appends the lines of every string in a decoded JSON value
+// to a slice. Numbers, booleans, and object keys are skipped and strings
+// containing HTML tags are parsed for their text.
+//
+// Args:
+//
+//	lines ([]string): The lines to append to
+//	value (interface{}): The decoded JSON value
+//
+// Returns:
+//
+//	[]string: The lines with the strings appended
+func appendJSONStrings(lines []string, value interface{}) []string {
+	if text, ok := value.(string); ok {
+		// Strings with markup such as rendered CMS content are parsed as HTML
+		if htmlTagPattern.MatchString(text) {
+			if htmlLines, err := ExtractHTMLText(text); err == nil {
+				return append(lines, htmlLines...)
+			}
+		}
+		for _, line := range strings.Split(html.UnescapeString(text), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	for _, child := range getJSONChildren(value) {
+		lines = appendJSONStrings(lines, child)
+	}
+	return lines
+}
+
+// docxPartPattern matches the DOCX parts that contain document text
+var docxPartPattern = regexp.MustCompile(`^word/(document|header\d*|footer\d*|footnotes|endnotes)\.xml$`)
+
+// ExtractDOCXText extracts the paragraphs of a DOCX document including the
+// headers, footers, footnotes, and endnotes
+//
+// Args:
+//
+//	data ([]byte): The contents of the document
+//
+// Returns:
+//
+//	[]string: The paragraphs in the document
+//	error: An error if one occurred
+func ExtractDOCXText(data []byte) ([]string, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, part := range sortZipFiles(zipReader.File) {
+		if !docxPartPattern.MatchString(part.Name) {
+			continue
+		}
+
+		partLines, err := extractXMLText(part, "t", "p")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", part.Name, err)
+		}
+		lines = append(lines, partLines...)
+	}
+
+	return lines, nil
+}
+
+// ExtractXLSXText extracts the shared and inline strings of an XLSX workbook
+//
+// Args:
+//
+//	data ([]byte): The contents of the workbook
+//
+// Returns:
+//
+//	[]string: The strings in the workbook
+//	error: An error if one occurred
+func ExtractXLSXText(data []byte) ([]string, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, part := range sortZipFiles(zipReader.File) {
+		var partLines []string
+		if part.Name == "xl/sharedStrings.xml" {
+			partLines, err = extractXMLText(part, "t", "si")
+		} else if strings.HasPrefix(part.Name, "xl/worksheets/") && strings.HasSuffix(part.Name, ".xml") {
+			partLines, err = extractXMLText(part, "t", "is")
+		} else {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", part.Name, err)
+		}
+		lines = append(lines, partLines...)
+	}
+
+	return lines, nil
+}
+
+// sortZipFiles returns the files of a zip archive sorted by name so output is
+// stable
+func sortZipFiles(files []*zip.File) []*zip.File {
+	sorted := append([]*zip.File{}, files...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// extractXMLText collects the character data of text elements in an Office
+// XML part and returns one line per block element
+func extractXMLText(part *zip.File, textElement string, blockElement string) ([]string, error) {
+	reader, err := part.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var lines []string
+	var line strings.Builder
+	inText := false
+
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case textElement:
+				inText = true
+			case "tab":
+				line.WriteString("\t")
+			case "br", "cr":
+				line.WriteString(" ")
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case textElement:
+				inText = false
+			case blockElement:
+				if line.Len() > 0 {
+					lines = append(lines, line.String())
+				}
+				line.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				line.Write(element)
+			}
+		}
+	}
+
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+
+	return lines, nil
+}
+
+// pdfStreamPattern matches a PDF object dictionary followed by a stream
+var pdfStreamPattern = regexp.MustCompile(`(?s)\bobj\b(.*?)\bstream\r?\n`)
+
+// ExtractPDFText extracts text from the content streams of a PDF document.
+// Streams compressed with FlateDecode are decompressed and text showing
+// operators are decoded. Text in fonts without a standard encoding may not
+// be recovered.
+//
+// Args:
+//
+//	data ([]byte): The contents of the document
+//
+// Returns:
+//
+//	[]string: The lines of text in the document
+//	error: An error if one occurred
+func ExtractPDFText(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\r\n\t "), []byte("%PDF-")) {
+		return nil, fmt.Errorf("missing PDF header")
+	}
+
+	var lines []string
+	offset := 0
+	for {
+		match := pdfStreamPattern.FindSubmatchIndex(data[offset:])
+		if match == nil {
+			break
+		}
+
+		// Objects without streams can be matched before the stream so only keep
+		// the dictionary of the last object
+		dictionary := data[offset+match[2] : offset+match[3]]
+		if index := bytes.LastIndex(dictionary, []byte("obj")); index != -1 {
+			dictionary = dictionary[index+3:]
+		}
+		start := offset + match[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end == -1 {
+			break
+		}
+		stream := data[start : start+end]
+		offset = start + end
+
+		// Skip images, fonts, and other binary streams
+		if bytes.Contains(dictionary, []byte("/Subtype")) || bytes.Contains(dictionary, []byte("/Length1")) || bytes.Contains(dictionary, []byte("/Type /XRef")) || bytes.Contains(dictionary, []byte("/Type/XRef")) {
+			continue
+		}
+
+		if bytes.Contains(dictionary, []byte("/FlateDecode")) {
+			reader, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			stream, err = io.ReadAll(reader)
+			reader.Close()
+			if err != nil && len(stream) == 0 {
+				continue
+			}
+		} else if bytes.Contains(dictionary, []byte("/Filter")) {
+			continue
+		}
+
+		lines = append(lines, ExtractPDFContentText(stream)...)
+	}
+
+	return lines, nil
+}
+
+// ExtractPDFContentText extracts the text shown by the text operators of a PDF
+// content stream. Positioning operators and the end of text objects start a
+// new line.
+//
+// Args:
+//
+//	content ([]byte): The decompressed content stream
+//
+// Returns:
+//
+//	[]string: The lines of text in the stream
+func ExtractPDFContentText(content []byte) []string {
+	var lines []string
+	var line strings.Builder
+	var operands []string
+	inText := false
+	inArray := false
+
+	flush := func() {
+		if strings.TrimSpace(line.String()) != "" {
+			lines = append(lines, strings.TrimSpace(line.String()))
+		}
+		line.Reset()
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '(':
+			str, next := readPDFLiteralString(content, i)
+			operands = append(operands, str)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end == -1 {
+				return lines
+			}
+			operands = append(operands, decodePDFHexString(content[i+1:i+end]))
+			i += end
+		case c == '[':
+			inArray = true
+		case c == ']':
+			inArray = false
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFRegular(c):
+			start := i
+			for i < len(content) && isPDFRegular(content[i]) {
+				i++
+			}
+			token := string(content[start:i])
+			i--
+
+			switch token {
+			case "BT":
+				inText = true
+				operands = nil
+			case "ET":
+				inText = false
+				flush()
+				operands = nil
+			case "Tj", "TJ":
+				if inText {
+					line.WriteString(strings.Join(operands, ""))
+				}
+				operands = nil
+			case "'", "\"":
+				if inText {
+					flush()
+					if len(operands) > 0 {
+						line.WriteString(operands[len(operands)-1])
+					}
+				}
+				operands = nil
+			case "T*", "Td", "TD", "Tm":
+				if inText {
+					flush()
+				}
+				operands = nil
+			default:
+				// Large negative kerning in TJ arrays separates words
+				if value, err := strconv.ParseFloat(token, 64); err == nil {
+					if inArray && value <= -200 {
+						operands = append(operands, " ")
+					}
+				} else if !strings.HasPrefix(token, "/") {
+					operands = nil
+				}
+			}
+		}
+	}
+	flush()
+
+	return lines
+}
+
+// isPDFRegular checks if a byte is a regular character in PDF syntax
+func isPDFRegular(c byte) bool {
+	return !strings.ContainsRune(" \t\r\n\f\x00()<>[]{}%", rune(c))
+}
+
+// readPDFLiteralString reads a literal string starting at the opening
+// parenthesis and returns the decoded string and the index of the closing
+// parenthesis
+func readPDFLiteralString(content []byte, start int) (string, int) {
+	var str []byte
+	depth := 0
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch escaped := content[i]; escaped {
+			case 'n':
+				str = append(str, '\n')
+			case 'r':
+				str = append(str, '\r')
+			case 't':
+				str = append(str, '\t')
+			case 'b':
+				str = append(str, '\b')
+			case 'f':
+				str = append(str, '\f')
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if escaped >= '0' && escaped <= '7' {
+					value := 0
+					j := 0
+					for ; j < 3 && i+j < len(content) && content[i+j] >= '0' && content[i+j] <= '7'; j++ {
+						value = value*8 + int(content[i+j]-'0')
+					}
+					str = append(str, byte(value))
+					i += j - 1
+				} else {
+					str = append(str, escaped)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				str = append(str, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(str), i
+			}
+			str = append(str, c)
+		default:
+			str = append(str, c)
+		}
+	}
+
+	return decodePDFString(str), len(content)
+}
+
+// decodePDFHexString decodes a hex string and drops it if it does not decode
+// to text
+func decodePDFHexString(data []byte) string {
+	digits := strings.Map(func(r rune) rune {
+		if strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return r
+		}
+		return -1
+	}, string(data))
+	if len(digits)%2 == 1 {
+		digits += "0"
+	}
+
+	decoded, err := hex.DecodeString(digits)
+	if err != nil {
+		return ""
+	}
+
+	return decodePDFString(decoded)
+}
+
+// decodePDFString decodes a PDF string as UTF-16BE when it starts with a byte
+// order mark and otherwise as PDFDocEncoding treated as Latin-1
+func decodePDFString(data []byte) string {
+	if bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+		var units []uint16
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, 0, len(data))
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			continue
+		}
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
+
+// ExtractMboxText extracts the text of every message in an mbox file.
+// Messages that cannot be parsed are skipped.
+//
+// Args:
+//
+//	data ([]byte): The contents of the mbox file
+//
+// Returns:
+//
+//	[]string: The lines of text in the messages
+//	error: An error if no message could be parsed
+func ExtractMboxText(data []byte) ([]string, error) {
+	var lines []string
+	var lastErr error
+	parsed := 0
+
+	for _, message := range SplitMbox(data) {
+		messageLines, err := ExtractEmailText(message)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		parsed++
+		lines = append(lines, messageLines...)
+	}
+
+	if parsed == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return lines, nil
+}
+
+// SplitMbox splits an mbox file into messages on the "From " separator lines
+// and unescapes ">From " lines in the message bodies
+//
+// Args:
+//
+//	data ([]byte): The contents of the mbox file
+//
+// Returns:
+//
+//	[][]byte: The messages in the mbox file
+func SplitMbox(data []byte) [][]byte {
+	var messages [][]byte
+	var message []byte
+	started := false
+
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if started && len(bytes.TrimSpace(message)) > 0 {
+				messages = append(messages, message)
+			}
+			message = nil
+			started = true
+			continue
+		}
+
+		trimmed := bytes.TrimLeft(line, ">")
+		if len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+			line = line[1:]
+		}
+		message = append(message, line...)
+	}
+
+	if len(bytes.TrimSpace(message)) > 0 {
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// ExtractEmailText extracts the subject, sender names, and the text and HTML
+// body parts of an email message. Quoted-printable and base64 parts are
+// decoded and attachments are skipped.
+//
+// Args:
+//
+//	data ([]byte): The contents of the email message
+//
+// Returns:
+//
+//	[]string: The lines of text in the message
+//	error: An error if one occurred
+func ExtractEmailText(data []byte) ([]string, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	decoder := &mime.WordDecoder{CharsetReader: getCharsetReader}
+
+	if subject := message.Header.Get("Subject"); subject != "" {
+		if decoded, err := decoder.DecodeHeader(subject); err == nil {
+			subject = decoded
+		}
+		lines = append(lines, subject)
+	}
+
+	parser := &mail.AddressParser{WordDecoder: decoder}
+	for _, field := range []string{"From", "Sender", "Reply-To"} {
+		addresses, err := parser.ParseList(message.Header.Get(field))
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			if address.Name != "" {
+				lines = append(lines, address.Name)
+			}
+		}
+	}
+
+	bodyLines, err := extractEmailPart(message.Header.Get("Content-Type"), message.Header.Get("Content-Transfer-Encoding"), message.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(lines, bodyLines...), nil
+}
+
+// extractEmailPart extracts the lines of text from a MIME part and recurses
+// into multipart and attached message parts. Only one part of a
+// multipart/alternative body is extracted so the same text is not counted
+// twice. The text/plain part is preferred and the first other part with text
+// is used otherwise.
+func extractEmailPart(contentType string, transferEncoding string, body io.Reader) ([]string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &base64Reader{reader: body})
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var lines, alternative []string
+		alternatives := mediaType == "multipart/alternative"
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				break
+			}
+
+			disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+			if disposition == "attachment" && part.Header.Get("Content-Type") != "message/rfc822" {
+				continue
+			}
+
+			partLines, err := extractEmailPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				continue
+			}
+
+			if !alternatives {
+				lines = append(lines, partLines...)
+				continue
+			}
+
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if part.Header.Get("Content-Type") == "" || partType == "text/plain" {
+				return partLines, nil
+			} else if alternative == nil && len(partLines) > 0 {
+				alternative = partLines
+			}
+		}
+
+		if alternatives {
+			return alternative, nil
+		}
+		return lines, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" && mediaType != "message/rfc822" {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType == "message/rfc822" {
+		return ExtractEmailText(data)
+	}
+
+	if charset := params["charset"]; charset != "" {
+		if reader, err := getCharsetReader(charset, bytes.NewReader(data)); err == nil {
+			if decoded, err := io.ReadAll(reader); err == nil {
+				data = decoded
+			}
+		}
+	}
+
+	if mediaType == "text/html" {
+		return ExtractHTMLText(string(data))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines, nil
+}
+
+// getCharsetReader returns a reader that decodes the charset to UTF-8
+func getCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+
+	return encoding.NewDecoder().Reader(input), nil
+}
+
+// base64Reader drops the whitespace that base64.NewDecoder does not ignore
+// such as spaces and tabs at the end of encoded lines
+type base64Reader struct {
+	reader io.Reader
+}
+
+// Read reads from the underlying reader and removes whitespace
+func (r *base64Reader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != ' ' && b != '\t' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// MaxWARCRecordSize is the maximum size in bytes of a WARC record block that
+// is read into memory. Larger records are skipped.
+const MaxWARCRecordSize = 64 * 1024 * 1024
+
+// WalkWARC reads the records of a WARC file and calls a function with the
+// target URI, content type, and body of every response and resource record.
+// HTTP responses are parsed so chunked and compressed bodies are decoded and
+// only successful and not found responses are returned like live URLs.
+// Records larger than MaxWARCRecordSize are skipped without reading them into
+// memory.
+//
+// Args:
+//
+//	reader (io.Reader): The reader of the WARC file
+//	fn (func(string, string, []byte) error): The function to call for each
+//	record
+//
+// Returns:
+//
+//	error: An error if one occurred
+func WalkWARC(reader io.Reader, fn func(uri string, contentType string, body []byte) error) error {
+	buffered := bufio.NewReader(reader)
+	headers := textproto.NewReader(buffered)
+
+	for {
+		// Skip the blank lines that end the previous record
+		version, err := headers.ReadLine()
+		for err == nil && version == "" {
+			version, err = headers.ReadLine()
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(version, "WARC/") {
+			return fmt.Errorf("invalid WARC record header %q", version)
+		}
+
+		header, err := headers.ReadMIMEHeader()
+		if err != nil {
+			return err
+		}
+
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return fmt.Errorf("invalid WARC record length %q", header.Get("Content-Length"))
+		}
+
+		// Other records and records over the maximum size are streamed past
+		// so a corrupt length can not exhaust memory
+		recordType := header.Get("WARC-Type")
+		if (recordType != "response" && recordType != "resource") || length > MaxWARCRecordSize {
+			if _, err := io.CopyN(io.Discard, buffered, length); err == io.EOF {
+				return io.ErrUnexpectedEOF
+			} else if err != nil {
+				return err
+			}
+			continue
+		}
+
+		block, err := io.ReadAll(io.LimitReader(buffered, length))
+		if err != nil {
+			return err
+		} else if int64(len(block)) < length {
+			return io.ErrUnexpectedEOF
+		}
+
+		uri := header.Get("WARC-Target-URI")
+		contentType := header.Get("Content-Type")
+
+		switch recordType {
+		case "response":
+			if !strings.HasPrefix(contentType, "application/http") {
+				continue
+			}
+
+			var body []byte
+			contentType, body = readWARCResponse(block)
+			if body == nil {
+				continue
+			}
+			if err := fn(uri, contentType, body); err != nil {
+				return err
+			}
+		case "resource":
+			if err := fn(uri, contentType, block); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readWARCResponse parses an HTTP response block and returns the content type
+// and decoded body. A nil body is returned for responses that should be
+// skipped.
+func readWARCResponse(block []byte) (string, []byte) {
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		return "", nil
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		// Some crawlers store the body without the transfer encoding so fall
+		// back to the raw bytes after the headers
+		index := bytes.Index(block, []byte("\r\n\r\n"))
+		if index == -1 {
+			return "", nil
+		}
+		body = block[index+4:]
+	}
+
+	var decoder io.Reader
+	switch strings.ToLower(response.Header.Get("Content-Encoding")) {
+	case "gzip", "x-gzip":
+		decoder, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		decoder, err = zlib.NewReader(bytes.NewReader(body))
+	}
+	if err == nil && decoder != nil {
+		if decoded, err := io.ReadAll(decoder); err == nil {
+			body = decoded
+		}
+	}
+
+	return response.Header.Get("Content-Type"), body
+}
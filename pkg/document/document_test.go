@@ -0,0 +1,627 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// ----------------------------------------------------------------------------
+// Functions with Unit Tests
+// ----------------------------------------------------------------------------
+// ** Extraction Functions **
+// - GetDocumentType()
+// - ExtractHTMLText()
+// - ParseHTMLSources()
+// - ExtractHTMLSources()
+// - ExtractHTMLFields()
+// - ExtractHTMLLinks()
+// - ExtractSitemapLocations()
+// - ParseJSONPath()
+// - ExtractJSONText()
+// - ExtractDOCXText()
+// - ExtractXLSXText()
+// - ExtractPDFText()
+// - ExtractPDFContentText()
+// - ExtractEmailText()
+// - ExtractMboxText()
+// - WalkWARC()
+//
+// ----------------------------------------------------------------------------
+// Functions without Unit Tests
+// ----------------------------------------------------------------------------
+// - IsDocumentFile() (Extraction Functions)
+// - ExtractDocumentText() (Extraction Functions)
+// - SplitMbox() (Extraction Functions)
+//
+
+// checkLinesEqual checks if two slices of lines are equal
+func checkLinesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// createTestZip creates a zip archive of the files for tests
+func createTestZip(t *testing.T, files [][2]string) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, file := range files {
+		member, err := writer.Create(file[0])
+		if err != nil {
+			t.Fatalf("zip.Create() error: %v", err)
+		}
+		member.Write([]byte(file[1]))
+	}
+	writer.Close()
+	return buffer.Bytes()
+}
+
+// createTestPDF creates a PDF document with a content stream for tests
+func createTestPDF(content string, compress bool) []byte {
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var buffer bytes.Buffer
+		writer := zlib.NewWriter(&buffer)
+		writer.Write(stream)
+		writer.Close()
+		stream = buffer.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	pdf.WriteString(fmt.Sprintf("4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter))
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("5 0 obj\n<< /Type /XObject /Subtype /Image /Length 4 >>\nstream\n(no) Tj\nendstream\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+// Unit Test for GetDocumentType()
+func TestGetDocumentType(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Name   string
+		Data   []byte
+		Output string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"page.html", nil, "html"},
+		{"page.HTM", nil, "html"},
+		{"report.docx", nil, "docx"},
+		{"budget.xlsx", nil, "xlsx"},
+		{"brochure.pdf", nil, "pdf"},
+		{"download", []byte("%PDF-1.7"), "pdf"},
+		{"message.eml", nil, "eml"},
+		{"archive.mbox", nil, "mbox"},
+		{"Inbox", []byte("From jane@example.com Mon Jan  1 00:00:00 2024\nSubject: Hi\n"), "mbox"},
+		{"crawl.warc", nil, "warc"},
+		{"crawl", []byte("WARC/1.0\r\n"), "warc"},
+		{"notes.txt", []byte("From the start\nof the notes"), "text"},
+		{"notes.txt", []byte("love"), "text"},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := GetDocumentType(testCase.Name, testCase.Data)
+		if given != testCase.Output {
+			t.Errorf("GetDocumentType(%v, %v) = %v; want %v", testCase.Name, testCase.Data, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ExtractHTMLText()
+func TestExtractHTMLText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"<html><body><p>Hello World</p><p>Love &amp; Peace</p></body></html>", []string{"Hello World", "Love & Peace"}},
+		{"<div>爱<b>情</b></div>", []string{"爱", "情"}},
+		{"<p>Kept</p><script>var skipped = 1;</script><style>p { color: red }</style>", []string{"Kept"}},
+		{"", nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractHTMLText(testCase.Input)
+		if err != nil || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractHTMLText(%v) = %q, %v; want %q", testCase.Input, given, err, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ExtractDOCXText()
+func TestExtractDOCXText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  []byte
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	document := `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>Acme </w:t></w:r><w:r><w:t>Corporation</w:t></w:r></w:p><w:p><w:r><w:t>Summer</w:t><w:tab/><w:t>2024</w:t></w:r></w:p><w:p></w:p></w:body></w:document>`
+	header := `<?xml version="1.0" encoding="UTF-8"?><w:hdr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:p><w:r><w:t>Confidential</w:t></w:r></w:p></w:hdr>`
+	styles := `<?xml version="1.0" encoding="UTF-8"?><w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:p><w:r><w:t>Ignored</w:t></w:r></w:p></w:styles>`
+
+	// Define test cases
+	testCases := TestCases{
+		{createTestZip(t, [][2]string{{"word/document.xml", document}, {"word/header1.xml", header}, {"word/styles.xml", styles}}), []string{"Acme Corporation", "Summer\t2024", "Confidential"}, false},
+		{[]byte("not a zip"), nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractDOCXText(testCase.Input)
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractDOCXText() = %q, %v; want %q, error %v", given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractXLSXText()
+func TestExtractXLSXText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  []byte
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8"?><sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Username</t></si><si><r><t>Pass</t></r><r><t>word</t></r></si></sst>`
+	sheet := `<?xml version="1.0" encoding="UTF-8"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row><c t="s"><v>0</v></c><c t="inlineStr"><is><t>Inline</t></is></c><c><v>42</v></c></row></sheetData></worksheet>`
+
+	// Define test cases
+	testCases := TestCases{
+		{createTestZip(t, [][2]string{{"xl/sharedStrings.xml", sharedStrings}, {"xl/worksheets/sheet1.xml", sheet}}), []string{"Username", "Password", "Inline"}, false},
+		{[]byte("not a zip"), nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractXLSXText(testCase.Input)
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractXLSXText() = %q, %v; want %q, error %v", given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractPDFText()
+func TestExtractPDFText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  []byte
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	content := "BT /F1 12 Tf 72 712 Td (Acme Corporation) Tj 0 -14 Td (Summer \\(2024\\)) Tj ET"
+
+	// Define test cases
+	testCases := TestCases{
+		{createTestPDF(content, false), []string{"Acme Corporation", "Summer (2024)"}, false},
+		{createTestPDF(content, true), []string{"Acme Corporation", "Summer (2024)"}, false},
+		{[]byte("not a pdf"), nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractPDFText(testCase.Input)
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractPDFText() = %q, %v; want %q, error %v", given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractPDFContentText()
+func TestExtractPDFContentText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"BT (Hello) Tj ( World) Tj ET", []string{"Hello World"}},
+		{"BT [(Hel) 20 (lo) -250 (World)] TJ ET", []string{"Hello World"}},
+		{"BT (Line1) Tj T* (Line2) Tj ET", []string{"Line1", "Line2"}},
+		{"BT (Line1) Tj (Line2) ' ET", []string{"Line1", "Line2"}},
+		{"BT <48656c6c6f> Tj ET", []string{"Hello"}},
+		{"BT <feff72314e00> Tj ET", []string{"爱一"}},
+		{"BT (caf\\351) Tj ET", []string{"café"}},
+		{"(Outside) Tj", nil},
+		{"% comment (ignored) Tj\nBT (Kept) Tj ET", []string{"Kept"}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := ExtractPDFContentText([]byte(testCase.Input))
+		if !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractPDFContentText(%v) = %q; want %q", testCase.Input, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ExtractEmailText()
+func TestExtractEmailText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"From: Jane Doe <jane@example.com>\r\nSubject: Project Falcon\r\n\r\nHello team\r\nThanks\r\n", []string{"Project Falcon", "Jane Doe", "Hello team", "Thanks", ""}, false},
+		{"From: =?UTF-8?B?5p2O5piO?= <li@example.com>\nSubject: =?UTF-8?Q?Caf=C3=A9_menu?=\nContent-Type: text/plain; charset=iso-8859-1\nContent-Transfer-Encoding: quoted-printable\n\nCaf=E9 au lait=\n today\n", []string{"Café menu", "李明", "Café au lait today", ""}, false},
+		{"Subject: Mixed\nMIME-Version: 1.0\nContent-Type: multipart/alternative; boundary=\"b1\"\n\n--b1\nContent-Type: text/plain\nContent-Transfer-Encoding: base64\n\nU3VtbWVyIHBp\nY25pYw==\n--b1\nContent-Type: text/html\n\n<p>Summer <b>picnic</b></p>\n--b1\nContent-Type: application/pdf\nContent-Disposition: attachment; filename=\"a.pdf\"\n\nJVBERi0=\n--b1--\n", []string{"Mixed", "Summer picnic"}, false},
+		{"Subject: Html\nContent-Type: multipart/alternative; boundary=\"b1\"\n\n--b1\nContent-Type: text/html\n\n<p>Summer <b>picnic</b></p>\n--b1\nContent-Type: text/calendar\n\nBEGIN:VCALENDAR\n--b1--\n", []string{"Html", "Summer ", "picnic"}, false},
+		{"Subject: Nested\nContent-Type: multipart/mixed; boundary=\"m1\"\n\n--m1\nContent-Type: multipart/alternative; boundary=\"b1\"\n\n--b1\nContent-Type: text/html\n\n<p>Summer picnic</p>\n--b1\nContent-Type: text/plain\n\nSummer picnic\n--b1--\n--m1\nContent-Type: text/plain\n\nSee you there\n--m1--\n", []string{"Nested", "Summer picnic", "See you there"}, false},
+		{"not an email", nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractEmailText([]byte(testCase.Input))
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractEmailText(%v) = %q, %v; want %q, error %v", testCase.Input, given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractMboxText()
+func TestExtractMboxText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"From jane@example.com Mon Jan  1 00:00:00 2024\nSubject: First\n\nBody one\n>From the desk\n\nFrom bob@example.com Mon Jan  1 00:00:00 2024\nFrom: Bob Smith <bob@example.com>\nSubject: Second\n\nBody two\n", []string{"First", "Body one", "From the desk", "", "", "Second", "Bob Smith", "Body two", ""}},
+		{"", nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractMboxText([]byte(testCase.Input))
+		if err != nil || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractMboxText(%v) = %q, %v; want %q", testCase.Input, given, err, testCase.Output)
+		}
+	}
+}
+
+// createTestWARC creates a WARC file with the records for tests
+func createTestWARC(records [][3]string) string {
+	var warc strings.Builder
+	for _, record := range records {
+		warc.WriteString("WARC/1.0\r\n")
+		warc.WriteString("WARC-Type: " + record[0] + "\r\n")
+		warc.WriteString("WARC-Target-URI: https://example.com/\r\n")
+		warc.WriteString("Content-Type: " + record[1] + "\r\n")
+		warc.WriteString(fmt.Sprintf("Content-Length: %d\r\n\r\n", len(record[2])))
+		warc.WriteString(record[2])
+		warc.WriteString("\r\n\r\n")
+	}
+	return warc.String()
+}
+
+// Unit Test for WalkWARC()
+func TestWalkWARC(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	response := "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>Acme Corporation</p>"
+	chunked := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nSummer\r\n0\r\n\r\n"
+	redirect := "HTTP/1.1 301 Moved Permanently\r\nContent-Type: text/html\r\nLocation: /new\r\n\r\n<p>Moved</p>"
+	missing := "HTTP/1.1 404 Not Found\r\nContent-Type: text/html\r\n\r\n<p>Missing</p>"
+	request := "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+
+	// Define test cases
+	testCases := TestCases{
+		{createTestWARC([][3]string{{"warcinfo", "application/warc-fields", "software: test\r\n"}, {"request", "application/http; msgtype=request", request}, {"response", "application/http; msgtype=response", response}}), []string{"https://example.com/ text/html; charset=utf-8 <p>Acme Corporation</p>"}, false},
+		{createTestWARC([][3]string{{"response", "application/http; msgtype=response", chunked}, {"response", "application/http; msgtype=response", redirect}, {"response", "application/http; msgtype=response", missing}}), []string{"https://example.com/ text/plain Summer", "https://example.com/ text/html <p>Missing</p>"}, false},
+		{createTestWARC([][3]string{{"resource", "text/html", "<p>Offline</p>"}, {"resource", "image/png", "PNG"}, {"response", "text/html", "<p>Not HTTP</p>"}}), []string{"https://example.com/ text/html <p>Offline</p>", "https://example.com/ image/png PNG"}, false},
+		{"", nil, false},
+		{"not a warc", nil, true},
+		{"WARC/1.0\r\nWARC-Type: resource\r\nContent-Length: 10\r\n\r\nshort", nil, true},
+		{"WARC/1.0\r\nWARC-Type: resource\r\nContent-Length: 999999999999\r\n\r\nshort", nil, true},
+		{"WARC/1.0\r\nWARC-Type: request\r\nContent-Length: 999999999999\r\n\r\nshort", nil, true},
+		{"WARC/1.0\r\nWARC-Type: resource\r\nWARC-Target-URI: https://example.com/\r\nContent-Type: text/plain\r\nContent-Length: 999999999999\r\n\r\n" + strings.Repeat("x", 1024), nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		var given []string
+		err := WalkWARC(strings.NewReader(testCase.Input), func(uri string, contentType string, body []byte) error {
+			given = append(given, uri+" "+contentType+" "+string(body))
+			return nil
+		})
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("WalkWARC(%q) = %q, %v; want %q, error %v", testCase.Input, given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractHTMLLinks()
+func TestExtractHTMLLinks(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Base   string
+		Output []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{`<a href="/about">About</a><a href="team.html#bio">Team</a><a href="/about">Again</a>`, "https://example.com/dir/", []string{"https://example.com/about", "https://example.com/dir/team.html"}},
+		{`<a href="mailto:a@example.com">Mail</a><a href="javascript:void(0)">JS</a><iframe src="http://example.org/embed"></iframe>`, "https://example.com/", []string{"http://example.org/embed"}},
+		{`<base href="https://cdn.example.com/docs/"><a href="guide">Guide</a>`, "https://example.com/", []string{"https://cdn.example.com/docs/guide"}},
+		{`<p>No links</p>`, "https://example.com/", nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		base, _ := url.Parse(testCase.Base)
+		given := ExtractHTMLLinks(testCase.Input, base)
+		if !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractHTMLLinks(%v, %v) = %q; want %q", testCase.Input, testCase.Base, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ExtractSitemapLocations()
+func TestExtractSitemapLocations(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input    string
+		Pages    []string
+		Sitemaps []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{`<?xml version="1.0"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc> https://example.com/ </loc></url><url><loc>https://example.com/about</loc><lastmod>2024-01-01</lastmod></url></urlset>`, []string{"https://example.com/", "https://example.com/about"}, nil},
+		{`<sitemapindex><sitemap><loc>https://example.com/pages.xml</loc></sitemap></sitemapindex>`, nil, []string{"https://example.com/pages.xml"}},
+		{`not xml`, nil, nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		pages, sitemaps := ExtractSitemapLocations([]byte(testCase.Input))
+		if !checkLinesEqual(pages, testCase.Pages) || !checkLinesEqual(sitemaps, testCase.Sitemaps) {
+			t.Errorf("ExtractSitemapLocations(%v) = %q, %q; want %q, %q", testCase.Input, pages, sitemaps, testCase.Pages, testCase.Sitemaps)
+		}
+	}
+}
+
+// Unit Test for ParseHTMLSources()
+func TestParseHTMLSources(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"text", []string{"text"}, false},
+		{" Title, meta ,email", []string{"title", "meta", "email"}, false},
+		{"text,all", HTMLSources, false},
+		{"", nil, false},
+		{"text,images", nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ParseHTMLSources(testCase.Input)
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ParseHTMLSources(%v) = %q, %v; want %q, error %v", testCase.Input, given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractHTMLSources()
+func TestExtractHTMLSources(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Sources []string
+		Lines   []string
+		Values  []string
+	}
+
+	type TestCases []TestCase
+
+	page := `<html><head><title>Acme Intranet</title>
+<meta name="keywords" content="falcon, summer sale"><meta property="og:description" content="Internal portal">
+<style>.hidden { display: none }</style><script>var token = "secret";</script></head>
+<body><p>Email jane.doe@acme.com or ping @acmecorp</p><a href="mailto:it@acme.com" title="Help desk">IT <b>Support</b></a>
+<img src="logo.png" alt="Falcon logo"></body></html>`
+
+	// Define test cases
+	testCases := TestCases{
+		{nil, []string{"Acme Intranet", "\n", "\n", "\n", "Email jane.doe@acme.com or ping @acmecorp", "IT Support", "\n"}, nil},
+		{[]string{"title"}, []string{"Acme Intranet"}, nil},
+		{[]string{"text", "title", "link"}, []string{"Acme Intranet", "\n", "\n", "\n", "Email jane.doe@acme.com or ping @acmecorp", "IT Support", "\n"}, nil},
+		{[]string{"meta"}, []string{"falcon", "summer sale", "Internal portal"}, nil},
+		{[]string{"attribute"}, []string{"Help desk", "Falcon logo"}, nil},
+		{[]string{"link"}, []string{"IT Support"}, nil},
+		{[]string{"email"}, nil, []string{"jane.doe@acme.com", "it@acme.com"}},
+		{[]string{"username"}, nil, []string{"jane.doe", "acmecorp", "it"}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		lines, values, err := ExtractHTMLSources(page, testCase.Sources)
+		if err != nil || !checkLinesEqual(lines, testCase.Lines) || !checkLinesEqual(values, testCase.Values) {
+			t.Errorf("ExtractHTMLSources(%v) = %q, %q, %v; want %q, %q", testCase.Sources, lines, values, err, testCase.Lines, testCase.Values)
+		}
+	}
+}
+
+// Unit Test for ExtractHTMLFields()
+func TestExtractHTMLFields(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []HTMLField
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"<title>Acme</title><p>Welcome</p>", []HTMLField{{"title", "Acme"}, {"text", "Welcome"}}},
+		{"<title> </title><p>Welcome</p>", []HTMLField{{"text", "Welcome"}}},
+		{"<p>Go <a href=\"/home\">back <b>home</b></a></p>", []HTMLField{{"text", "Go "}, {"link", "back home"}}},
+		{"<a href=\"/team\">Ask @acmecorp</a>", []HTMLField{{"link", "Ask @acmecorp"}, {"username", "acmecorp"}}},
+		{"<a href=\"/empty\"></a>", nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractHTMLFields(testCase.Input)
+		if err != nil || fmt.Sprint(given) != fmt.Sprint(testCase.Output) {
+			t.Errorf("ExtractHTMLFields(%v) = %v, %v; want %v", testCase.Input, given, err, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ParseJSONPath()
+func TestParseJSONPath(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"$", nil, false},
+		{"$.items[*].title", []string{"items", "*", "title"}, false},
+		{"data.users.0.name", []string{"data", "users", "0", "name"}, false},
+		{"$['display name'][-1]", []string{"display name", "-1"}, false},
+		{`$..["description"]`, []string{"..", "description"}, false},
+		{"$..name", []string{"..", "name"}, false},
+		{"$.items[", nil, true},
+		{"$.items[title]", nil, true},
+		{"$.items.", nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ParseJSONPath(testCase.Input)
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ParseJSONPath(%v) = %q, %v; want %q, error %v", testCase.Input, given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ExtractJSONText()
+func TestExtractJSONText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Paths  []string
+		Output []string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	response := `{"count": 2, "results": [{"name": "Falcon Project", "tags": ["summer", "sale"], "active": true},
+{"name": "Acme Labs", "about": {"description": "Line one\nLine two"}}], "content": "<p>Rendered &amp; <b>bold</b></p>"}`
+
+	// Define test cases
+	testCases := TestCases{
+		{response, nil, []string{"Rendered & ", "bold", "Falcon Project", "summer", "sale", "Line one", "Line two", "Acme Labs"}, false},
+		{response, []string{"$.results[*].name"}, []string{"Falcon Project", "Acme Labs"}, false},
+		{response, []string{"results.0.tags", "$..description"}, []string{"summer", "sale", "Line one", "Line two"}, false},
+		{response, []string{"$.results[-1].name", "$.missing"}, []string{"Acme Labs"}, false},
+		{"{\"word\": \"first\"}\n{\"word\": \"second\"}\n", []string{"word"}, []string{"first", "second"}, false},
+		{"\xef\xbb\xbf[\"caf\\u00e9\", 1, null]", nil, []string{"café"}, false},
+		{"{\"word\": ", nil, nil, true},
+		{response, []string{"$.results["}, nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ExtractJSONText([]byte(testCase.Input), testCase.Paths)
+		if (err != nil) != testCase.Error || !checkLinesEqual(given, testCase.Output) {
+			t.Errorf("ExtractJSONText(%v, %v) = %q, %v; want %q, error %v", testCase.Input, testCase.Paths, given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
@@ -2,10 +2,15 @@
 package format
 
 import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html"
+	"io"
+	"math"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -13,9 +18,15 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/jakewnuk/ptt/pkg/mask"
 	"github.com/jakewnuk/ptt/pkg/models"
+	"github.com/jakewnuk/ptt/pkg/utils"
+
+	"github.com/ulikunitz/xz/lzma"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 // ----------------------------------------------------------------------------
@@ -36,7 +47,7 @@ func PrintArrayToSTDOUT(freq map[string]int, verbose bool) {
 	p := make(models.PairList, len(freq))
 	i := 0
 	for k, v := range freq {
-		p[i] = models.Pair{k, v}
+		p[i] = models.Pair{Key: k, Value: v}
 		i++
 	}
 	sort.Sort(sort.Reverse(p))
@@ -66,7 +77,7 @@ func PrintArrayToMarkdown(freq map[string]int, command string) {
 	p := make(models.PairList, len(freq))
 	i := 0
 	for k, v := range freq {
-		p[i] = models.Pair{k, v}
+		p[i] = models.Pair{Key: k, Value: v}
 		i++
 	}
 	sort.Sort(sort.Reverse(p))
@@ -99,8 +110,8 @@ func PrintStatsToSTDOUT(freq map[string]int, verbose bool, maxItems int) {
 	normalizedP := make(models.PairList, len(freq))
 	i := 0
 	for k, v := range freq {
-		p[i] = models.Pair{k, v}
-		normalizedP[i] = models.Pair{k, v}
+		p[i] = models.Pair{Key: k, Value: v}
+		normalizedP[i] = models.Pair{Key: k, Value: v}
 		i++
 	}
 	sort.Sort(sort.Reverse(p))
@@ -170,7 +181,7 @@ func CreateVerboseStats(freq map[string]int) string {
 	p := make(models.PairList, len(freq))
 	i := 0
 	for k, v := range freq {
-		p[i] = models.Pair{k, v}
+		p[i] = models.Pair{Key: k, Value: v}
 		i++
 	}
 	sort.Sort(sort.Reverse(p))
@@ -181,6 +192,7 @@ func CreateVerboseStats(freq map[string]int) string {
 	lengths := make([]int, 0)
 	frequencies := make([]int, 0)
 	complexities := make([]int, 0)
+	entropies := make([]int, 0)
 	categoryCounts := make(map[string]int)
 	for k, v := range freq {
 		totalWords += len(strings.Fields(k))
@@ -195,6 +207,7 @@ func CreateVerboseStats(freq map[string]int) string {
 		m := mask.MakeMaskedString(k, "uldbs")
 		complexity := mask.TestMaskComplexity(m)
 		complexities = append(complexities, complexity)
+		entropies = append(entropies, int(math.Round(mask.CalculateEntropy(k, nil))))
 
 		for _, category := range categories {
 			categoryCounts[category]++
@@ -217,6 +230,9 @@ func CreateVerboseStats(freq map[string]int) string {
 	plot, minBW, q1, q2, q3, maxBW = CreateBoxAndWhiskersPlot(complexities)
 	stats += fmt.Sprintf("Item Complexity: %s\n", plot)
 	stats += fmt.Sprintf("Min: %d, Q1: %d, Q2: %d, Q3: %d, Max: %d\n", minBW, q1, q2, q3, maxBW)
+	plot, minBW, q1, q2, q3, maxBW = CreateBoxAndWhiskersPlot(entropies)
+	stats += fmt.Sprintf("Item Entropy: %s\n", plot)
+	stats += fmt.Sprintf("Min: %d, Q1: %d, Q2: %d, Q3: %d, Max: %d\n", minBW, q1, q2, q3, maxBW)
 
 	stats += "\nCategory Counts:\n"
 	for category, count := range categoryCounts {
@@ -226,6 +242,108 @@ func CreateVerboseStats(freq map[string]int) string {
 	return stats
 }
 
+// EmptyNTHash is the NT hash of an empty password
+const EmptyNTHash = "31d6cfe0d16ae931b73c59d7e0c089c0"
+
+// CreateCredentialStats creates a string of statistics about accounts parsed
+// from credential dumps including cracked totals, passwords based on the
+// username, and passwords shared across accounts. Usernames shorter than
+// three characters are not checked for containment. Accounts with the empty
+// password NT hash are counted as empty passwords instead of cracked since
+// the hash is known without cracking and is common for disabled accounts.
+//
+// Args:
+//
+//	credentials ([]models.Credential): The accounts to analyze
+//	maxItems (int): The maximum number of items to list in each section
+//
+// Returns:
+//
+//	string: A string of credential statistics
+func CreateCredentialStats(credentials []models.Credential, maxItems int) string {
+	var stats string
+	if maxItems == 0 {
+		maxItems = 10
+	}
+
+	cracked := 0
+	empty := 0
+	matchesUsername := make([]string, 0)
+	containsUsername := make([]string, 0)
+	sharedAccounts := make(map[string][]string)
+	for _, credential := range credentials {
+		if strings.EqualFold(credential.NTHash, EmptyNTHash) {
+			empty++
+			continue
+		} else if !credential.Cracked {
+			continue
+		}
+		cracked++
+		sharedAccounts[credential.Plaintext] = append(sharedAccounts[credential.Plaintext], credential.Username)
+
+		username := strings.ToLower(credential.Username)
+		plaintext := strings.ToLower(credential.Plaintext)
+		if plaintext == username {
+			matchesUsername = append(matchesUsername, credential.Username)
+		} else if len(username) >= 3 && strings.Contains(plaintext, username) {
+			containsUsername = append(containsUsername, fmt.Sprintf("%s: %s", credential.Username, credential.Plaintext))
+		}
+	}
+
+	shared := make(models.PairList, 0)
+	for plaintext, accounts := range sharedAccounts {
+		if len(accounts) > 1 {
+			shared = append(shared, models.Pair{Key: plaintext, Value: len(accounts)})
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		if shared[i].Value != shared[j].Value {
+			return shared[i].Value > shared[j].Value
+		}
+		return shared[i].Key < shared[j].Key
+	})
+
+	percent := 0.0
+	if len(credentials) > 0 {
+		percent = float64(cracked) / float64(len(credentials)) * 100
+	}
+
+	stats += "Credential Stats:\n"
+	stats += fmt.Sprintf("Total Accounts: %d\n", len(credentials))
+	stats += fmt.Sprintf("Cracked Accounts: %d (%.2f%%)\n", cracked, percent)
+	stats += fmt.Sprintf("Empty Passwords: %d\n", empty)
+	stats += fmt.Sprintf("Unique Passwords: %d\n", len(sharedAccounts))
+	stats += fmt.Sprintf("Password Matches Username: %d\n", len(matchesUsername))
+	stats += fmt.Sprintf("Password Contains Username: %d\n", len(containsUsername))
+	stats += fmt.Sprintf("Shared Passwords: %d\n", len(shared))
+
+	if len(shared) > 0 {
+		stats += "\nShared Passwords:\n"
+		for _, pair := range shared[:min(maxItems, len(shared))] {
+			accounts := sharedAccounts[pair.Key]
+			sort.Strings(accounts)
+			if len(accounts) > 5 {
+				accounts = append(accounts[:5:5], "...")
+			}
+			stats += fmt.Sprintf("%s [%d]: %s\n", pair.Key, pair.Value, strings.Join(accounts, ", "))
+		}
+	}
+
+	if len(matchesUsername) > 0 || len(containsUsername) > 0 {
+		sort.Strings(matchesUsername)
+		sort.Strings(containsUsername)
+		stats += "\nPasswords Based on Username:\n"
+		for _, username := range matchesUsername[:min(maxItems, len(matchesUsername))] {
+			stats += fmt.Sprintf("%s: %s\n", username, "[username]")
+		}
+		for _, item := range containsUsername[:min(maxItems, len(containsUsername))] {
+			stats += item + "\n"
+		}
+	}
+
+	return stats
+}
+
 // StatClassifyToken classifies a token into a set of categories
 // based on the token's content. "Short" and "long" are relative
 // to ten characters currently.
@@ -418,6 +536,9 @@ func CreateBoxAndWhiskersPlot(data []int) (string, int, int, int, int, int) {
 
 	// Normalize the plot
 	largest := maxBW
+	if largest == 0 {
+		largest = 1
+	}
 	normalizedQ1 := q1 * 50 / largest
 	normalizedQ2 := q2 * 50 / largest
 	normalizedQ3 := q3 * 50 / largest
@@ -461,6 +582,310 @@ func SaveArrayToJSON(path string, freq map[string]int) error {
 	return nil
 }
 
+// CreateProvenanceRecords creates a record of the count and sources of every
+// item sorted by frequency and then by item
+//
+// Args:
+//
+//	freq (map[string]int): A map of item frequencies
+//	provenance (map[string][]string): The sources that produced each item
+//
+// Returns:
+//
+//	[]models.ProvenanceRecord: The records of each item with sorted sources
+func CreateProvenanceRecords(freq map[string]int, provenance map[string][]string) []models.ProvenanceRecord {
+	records := make([]models.ProvenanceRecord, 0, len(freq))
+	for item, count := range freq {
+		sources := append([]string{}, provenance[item]...)
+		sort.Strings(sources)
+		records = append(records, models.ProvenanceRecord{Item: item, Count: count, Sources: sources})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Count != records[j].Count {
+			return records[i].Count > records[j].Count
+		}
+		return records[i].Item < records[j].Item
+	})
+
+	return records
+}
+
+// SaveProvenance saves the count and sources of every item to a file. Files
+// ending in .csv are saved as CSV with item, count, and sources columns where
+// sources are separated by semicolons and other files are saved as a JSON
+// array of records.
+//
+// Args:
+//
+//	path (string): The path to save the file
+//	freq (map[string]int): A map of item frequencies
+//	provenance (map[string][]string): The sources that produced each item
+//
+// Returns:
+//
+//	error: An error if the file cannot be saved
+func SaveProvenance(path string, freq map[string]int, provenance map[string][]string) error {
+	records := CreateProvenanceRecords(freq, provenance)
+
+	// Check if the directory exists
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("directory does not exist: %s", dir)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create provenance file: %s", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		writer := csv.NewWriter(file)
+		writer.Write([]string{"item", "count", "sources"})
+		for _, record := range records {
+			writer.Write([]string{record.Item, strconv.Itoa(record.Count), strings.Join(record.Sources, ";")})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("failed to write provenance to file: %s", err)
+		}
+		return nil
+	}
+
+	if err := json.NewEncoder(file).Encode(records); err != nil {
+		return fmt.Errorf("failed to write provenance to file: %s", err)
+	}
+
+	return nil
+}
+
+// OutputFormats are the structured formats the output can be written in
+var OutputFormats = []string{"jsonl", "csv"}
+
+// OutputFields are the metadata fields that can be written with each item in
+// JSON Lines and CSV output
+var OutputFields = []string{"mask", "length", "category", "source", "hashtype"}
+
+// ParseOutputFields parses a comma separated list of output metadata fields.
+// The "all" field selects every field.
+//
+// Args:
+//
+//	list (string): The comma separated fields
+//
+// Returns:
+//
+//	[]string: The selected fields in the order of OutputFields
+//	error: An error if a field is not known
+func ParseOutputFields(list string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if field == "all" {
+			return OutputFields, nil
+		}
+
+		known := false
+		for _, name := range OutputFields {
+			known = known || name == field
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown output field %s", field)
+		}
+		selected[field] = true
+	}
+
+	var fields []string
+	for _, name := range OutputFields {
+		if selected[name] {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields, nil
+}
+
+// CreateOutputRecord creates the output record of an item with the metadata
+// fields selected in the output options. The length is in bytes like the -l
+// length filter and categories and sources are sorted.
+//
+// Args:
+//
+//	item (string): The item to create the record for
+//	count (int): The frequency of the item
+//	options (models.OutputOptions): The selected fields, the replacement mask
+//	used for the mask field, the provenance used for the source field, and
+//	the potfile hash types used for the hashtype field
+//
+// Returns:
+//
+//	models.OutputRecord: The record of the item
+func CreateOutputRecord(item string, count int, options models.OutputOptions) models.OutputRecord {
+	record := models.OutputRecord{Item: item, Count: count}
+	for _, field := range options.Fields {
+		switch field {
+		case "mask":
+			record.Mask = mask.MakeMaskedString(item, options.ReplacementMask)
+		case "length":
+			record.Length = len(item)
+		case "category":
+			record.Category = StatClassifyToken(item)
+			sort.Strings(record.Category)
+		case "source":
+			record.Sources = append([]string{}, options.Provenance[item]...)
+			sort.Strings(record.Sources)
+		case "hashtype":
+			record.HashTypes = append([]string{}, options.HashTypes[item]...)
+			sort.Strings(record.HashTypes)
+		}
+	}
+
+	return record
+}
+
+// WriteOutputRecords writes one record per item to a writer as JSON Lines or
+// CSV sorted by frequency and then by item. CSV output starts with a header of
+// item, count, and the selected fields and joins lists with semicolons. JSON
+// Lines output is always UTF-8 so items that are not valid UTF-8 after
+// encoding are written in the $HEX[...] format. Items that are already in the
+// $HEX[...] format are encoded again so they are imported unchanged.
+//
+// Args:
+//
+//	writer (io.Writer): The writer to write the records to
+//	freq (map[string]int): A map of item frequencies
+//	options (models.OutputOptions): The format, metadata fields, and output
+//	encoding of the records
+//
+// Returns:
+//
+//	error: An error if the format or encoding is not supported or the records
+//	cannot be written
+func WriteOutputRecords(writer io.Writer, freq map[string]int, options models.OutputOptions) error {
+	var encoder *encoding.Encoder
+	if options.Encoding != "" {
+		var err error
+		encoder, err = GetOutputEncoder(options.Encoding)
+		if err != nil {
+			return err
+		}
+	}
+
+	p := make(models.PairList, 0, len(freq))
+	for k, v := range freq {
+		p = append(p, models.Pair{Key: k, Value: v})
+	}
+	sort.Slice(p, func(i, j int) bool {
+		if p[i].Value != p[j].Value {
+			return p[i].Value > p[j].Value
+		}
+		return p[i].Key < p[j].Key
+	})
+
+	buffered := bufio.NewWriter(writer)
+	switch options.Format {
+	case "jsonl":
+		jsonEncoder := json.NewEncoder(buffered)
+		jsonEncoder.SetEscapeHTML(false)
+		for _, pair := range p {
+			record := CreateOutputRecord(pair.Key, pair.Value, options)
+			if utils.CheckHexString(record.Item) {
+				record.Item = "$HEX[" + hex.EncodeToString([]byte(record.Item)) + "]"
+			} else if options.Encoding != "" {
+				record.Item = EncodeOutputString(record.Item, encoder)
+			}
+			if !utf8.ValidString(record.Item) {
+				record.Item = "$HEX[" + hex.EncodeToString([]byte(record.Item)) + "]"
+			}
+			if err := jsonEncoder.Encode(record); err != nil {
+				return fmt.Errorf("failed to write JSON Lines record: %s", err)
+			}
+		}
+	case "csv":
+		csvWriter := csv.NewWriter(buffered)
+		header := []string{"item", "count"}
+		columns := map[string]string{"source": "sources", "hashtype": "hashtypes"}
+		for _, field := range options.Fields {
+			if column, ok := columns[field]; ok {
+				field = column
+			}
+			header = append(header, field)
+		}
+		csvWriter.Write(header)
+
+		for _, pair := range p {
+			record := CreateOutputRecord(pair.Key, pair.Value, options)
+			if utils.CheckHexString(record.Item) {
+				record.Item = "$HEX[" + hex.EncodeToString([]byte(record.Item)) + "]"
+			} else if options.Encoding != "" {
+				record.Item = EncodeOutputString(record.Item, encoder)
+			}
+
+			row := []string{record.Item, strconv.Itoa(record.Count)}
+			for _, field := range options.Fields {
+				switch field {
+				case "mask":
+					row = append(row, record.Mask)
+				case "length":
+					row = append(row, strconv.Itoa(record.Length))
+				case "category":
+					row = append(row, strings.Join(record.Category, ";"))
+				case "source":
+					row = append(row, strings.Join(record.Sources, ";"))
+				case "hashtype":
+					row = append(row, strings.Join(record.HashTypes, ";"))
+				}
+			}
+			csvWriter.Write(row)
+		}
+
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return fmt.Errorf("failed to write CSV record: %s", err)
+		}
+	default:
+		return fmt.Errorf("unknown output format %s", options.Format)
+	}
+
+	return buffered.Flush()
+}
+
+// SaveOutputRecords saves one record per item to a file as JSON Lines or CSV
+//
+// Args:
+//
+//	path (string): The path to save the file
+//	freq (map[string]int): A map of item frequencies
+//	options (models.OutputOptions): The format, metadata fields, and output
+//	encoding of the records
+//
+// Returns:
+//
+//	error: An error if the file cannot be saved
+func SaveOutputRecords(path string, freq map[string]int, options models.OutputOptions) error {
+	// Check if the directory exists
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("directory does not exist: %s", dir)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %s", err)
+	}
+	defer file.Close()
+
+	if err := WriteOutputRecords(file, freq, options); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
 // RetainRemove compares a string against a list of words to retain and remove
 //
 // Args:
@@ -532,6 +957,30 @@ func RemoveMinimumFrequency(freq map[string]int, minF int) map[string]int {
 	return newFreq
 }
 
+// RemoveMinimumEntropy removes items from a map that are below a minimum
+// entropy threshold and returns a new map. Items can be strings, full masks,
+// or partial masks.
+//
+// Args:
+//
+//	freq (map[string]int): A map of item frequencies
+//	minE (float64): The minimum entropy threshold in bits
+//	dictionary (map[string]int): Dictionary of tokens to adjust for
+//
+// Returns:
+//
+//	(map[string]int): A new map of item frequencies above the minimum threshold
+func RemoveMinimumEntropy(freq map[string]int, minE float64, dictionary map[string]int) map[string]int {
+	newFreq := make(map[string]int)
+	index := mask.NewRetainIndex(dictionary)
+	for key, value := range freq {
+		if mask.CalculateEntropy(key, index) >= minE {
+			newFreq[key] = value
+		}
+	}
+	return newFreq
+}
+
 // RemoveLengthRange removes items from a map that are outside of a length
 // range or not equal to the start of the end if no end is provided. The length
 // of the range is inclusive.
@@ -575,7 +1024,7 @@ func FilterTopN(freq map[string]int, n int) map[string]int {
 	p := make(models.PairList, len(freq))
 	i := 0
 	for k, v := range freq {
-		p[i] = models.Pair{k, v}
+		p[i] = models.Pair{Key: k, Value: v}
 		i++
 	}
 	sort.Sort(sort.Reverse(p))
@@ -610,6 +1059,189 @@ func CreateIgnoreCaseMap(freq map[string]int) map[string]int {
 	return newFreq
 }
 
+// CreateIgnoreCaseProvenance creates a new map of item sources with
+// case-insensitive keys to match CreateIgnoreCaseMap. The sources of keys
+// that only differ by case are combined.
+//
+// Args:
+// provenance (map[string][]string): The sources that produced each item
+//
+// Returns:
+// map[string][]string: A new map of item sources with case-insensitive keys
+func CreateIgnoreCaseProvenance(provenance map[string][]string) map[string][]string {
+	newProvenance := make(map[string][]string)
+	for key, sources := range provenance {
+		key = strings.ToLower(key)
+		for _, source := range sources {
+			known := false
+			for _, existing := range newProvenance[key] {
+				known = known || existing == source
+			}
+			if !known {
+				newProvenance[key] = append(newProvenance[key], source)
+			}
+		}
+	}
+	return newProvenance
+}
+
+// ----------------------------------------------------------------------------
+// Markov Functions
+// ----------------------------------------------------------------------------
+
+// MarkovMaxLength is the number of positions in the hashcat Markov tables
+const MarkovMaxLength = 256
+
+// MarkovCharsetSize is the number of characters in the hashcat Markov tables
+const MarkovCharsetSize = 256
+
+// MarkovHcstat2Version is the hcstat2 file header with the format version
+const MarkovHcstat2Version uint64 = 0x6863737461740002
+
+// CreateMarkovMap creates a map of per-position character frequencies and
+// per-position bigram frequencies from the input map. Characters are counted
+// by byte to match the hashcat Markov tables. Position keys use the format
+// "position:character" and bigram keys use the format "position:bigram"
+// where the position is the index of the first character starting at 0.
+//
+// Args:
+//
+//	input (map[string]int): A map of input strings
+//	bypass (bool): If true, the map is not used for output or filtering
+//	debug (bool): If true, print additional debug information to stderr
+//
+// Returns:
+//
+//	(map[string]int): A new map of position and bigram frequencies
+func CreateMarkovMap(input map[string]int, bypass bool, debug bool) map[string]int {
+	output := make(map[string]int)
+	for k, v := range input {
+		var keys []string
+		for i := 0; i < len(k) && i < MarkovMaxLength; i++ {
+			keys = append(keys, fmt.Sprintf("%d:%s", i, FormatMarkovByte(k[i])))
+			if i+1 < len(k) && i+1 < MarkovMaxLength {
+				keys = append(keys, fmt.Sprintf("%d:%s%s", i, FormatMarkovByte(k[i]), FormatMarkovByte(k[i+1])))
+			}
+		}
+
+		if debug {
+			fmt.Fprintf(os.Stderr, "[?] CreateMarkovMap:\n")
+			fmt.Fprintf(os.Stderr, "Input: %s\n", k)
+			fmt.Fprintf(os.Stderr, "Statistics: %v\n", keys)
+		}
+
+		for _, key := range keys {
+			if !bypass {
+				output[key] += v
+			} else {
+				fmt.Println(key)
+			}
+		}
+	}
+	return output
+}
+
+// FormatMarkovByte formats a single byte for human-readable Markov output.
+// Printable ASCII characters are returned as is and all other bytes are
+// returned in the \xFF format.
+//
+// Args:
+//
+//	b (byte): The byte to format
+//
+// Returns:
+//
+//	(string): The formatted byte
+func FormatMarkovByte(b byte) string {
+	if b < 0x20 || b > 0x7e {
+		return fmt.Sprintf("\\x%02X", b)
+	}
+	return string(b)
+}
+
+// SaveMarkovToHcstat2 saves per-position character and bigram frequencies
+// of the input map to a hashcat compatible .hcstat2 file. The statistics are
+// written in the hcstat2gen layout and compressed as a raw LZMA2 stream.
+//
+// Args:
+//
+//	path (string): The path to save the .hcstat2 file
+//	freq (map[string]int): A map of item frequencies
+//
+// Returns:
+//
+//	error: An error if the file cannot be saved
+func SaveMarkovToHcstat2(path string, freq map[string]int) error {
+	rootStats := make([]uint64, MarkovMaxLength*MarkovCharsetSize)
+	markovStats := make(map[int]uint64)
+
+	for k, v := range freq {
+		for i := 0; i < len(k) && i < MarkovMaxLength; i++ {
+			rootStats[i*MarkovCharsetSize+int(k[i])] += uint64(v)
+			if i+1 < len(k) && i+1 < MarkovMaxLength {
+				markovStats[(i*MarkovCharsetSize+int(k[i]))*MarkovCharsetSize+int(k[i+1])] += uint64(v)
+			}
+		}
+	}
+
+	// Check if the directory exists
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("directory does not exist: %s", dir)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create hcstat2 file: %s", err)
+	}
+	defer file.Close()
+
+	compressor, err := lzma.Writer2Config{DictCap: 8 * 1024 * 1024}.NewWriter2(file)
+	if err != nil {
+		return fmt.Errorf("failed to create LZMA2 writer: %s", err)
+	}
+	writer := bufio.NewWriter(compressor)
+
+	// Header is the version followed by zero padding then the root table
+	// and the Markov table
+	buffer := make([]byte, 8)
+	writeUint64 := func(value uint64) error {
+		binary.BigEndian.PutUint64(buffer, value)
+		if _, err := writer.Write(buffer); err != nil {
+			return fmt.Errorf("failed to write hcstat2 data to file: %s", err)
+		}
+		return nil
+	}
+
+	for _, value := range []uint64{MarkovHcstat2Version, 0} {
+		if err := writeUint64(value); err != nil {
+			return err
+		}
+	}
+
+	for _, count := range rootStats {
+		if err := writeUint64(count); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < MarkovMaxLength*MarkovCharsetSize*MarkovCharsetSize; i++ {
+		if err := writeUint64(markovStats[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write hcstat2 data to file: %s", err)
+	}
+
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("failed to compress hcstat2 data: %s", err)
+	}
+
+	return nil
+}
+
 // ----------------------------------------------------------------------------
 // Encoding Functions
 // ----------------------------------------------------------------------------
@@ -872,3 +1504,90 @@ func HexEncodeMap(input map[string]int, bypass bool, debug bool) map[string]int
 	}
 	return output
 }
+
+// EncodeOutputMap transcodes a map of UTF-8 strings to the named encoding for
+// output. Strings with invalid UTF-8 or characters the encoding cannot
+// represent are written in the $HEX[...] format. The "hex" encoding only
+// converts strings with invalid UTF-8.
+//
+// Args:
+//
+//	input (map[string]int): A map of input strings
+//	outputEncoding (string): The name of the encoding such as latin1,
+//	windows-1252, shift_jis, or hex
+//
+// Returns:
+//
+//	(map[string]int): A new map of encoded strings
+//	(error): An error if the encoding is not supported
+func EncodeOutputMap(input map[string]int, outputEncoding string) (map[string]int, error) {
+	output := make(map[string]int)
+
+	encoder, err := GetOutputEncoder(outputEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range input {
+		output[EncodeOutputString(k, encoder)] += v
+	}
+
+	return output, nil
+}
+
+// GetOutputEncoder returns the encoder for the named output encoding. The
+// "hex" encoding returns a nil encoder that only converts invalid UTF-8.
+// UTF-16 encodings are not supported because items are encoded one at a time
+// and separated by single byte newlines.
+//
+// Args:
+//
+//	outputEncoding (string): The name of the encoding such as latin1,
+//	windows-1252, shift_jis, or hex
+//
+// Returns:
+//
+//	(*encoding.Encoder): The encoder or nil for the hex encoding
+//	(error): An error if the encoding is not supported
+func GetOutputEncoder(outputEncoding string) (*encoding.Encoder, error) {
+	if strings.EqualFold(outputEncoding, "hex") {
+		return nil, nil
+	}
+
+	outputCharset, err := htmlindex.Get(outputEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported encoding %s", outputEncoding)
+	} else if name, _ := htmlindex.Name(outputCharset); strings.HasPrefix(name, "utf-16") {
+		return nil, fmt.Errorf("unsupported encoding %s because UTF-16 output is not supported", outputEncoding)
+	}
+
+	return outputCharset.NewEncoder(), nil
+}
+
+// EncodeOutputString transcodes a UTF-8 string for output. Strings with
+// invalid UTF-8 or characters the encoder cannot represent are written in the
+// $HEX[...] format.
+//
+// Args:
+//
+//	input (string): The string to encode
+//	encoder (*encoding.Encoder): The encoder to use or nil to only convert
+//	invalid UTF-8
+//
+// Returns:
+//
+//	(string): The encoded string
+func EncodeOutputString(input string, encoder *encoding.Encoder) string {
+	if !utf8.ValidString(input) {
+		return "$HEX[" + hex.EncodeToString([]byte(input)) + "]"
+	} else if encoder == nil {
+		return input
+	}
+
+	encoded, err := encoder.String(input)
+	if err != nil {
+		return "$HEX[" + hex.EncodeToString([]byte(input)) + "]"
+	}
+
+	return encoded
+}
@@ -1,9 +1,19 @@
 package format
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 
+	"github.com/jakewnuk/ptt/pkg/models"
 	"github.com/jakewnuk/ptt/pkg/utils"
+
+	"github.com/ulikunitz/xz/lzma"
 )
 
 // ----------------------------------------------------------------------------
@@ -13,7 +23,14 @@ import (
 // - RetainRemove()
 // - RemoveMinimumFrequency()
 // - RemoveLengthRange()
+// - RemoveMinimumEntropy()
 // - FilterTopN()
+// - CreateCredentialStats()
+// - CreateProvenanceRecords()
+// - CreateIgnoreCaseProvenance()
+// - ParseOutputFields()
+// - CreateOutputRecord()
+// - WriteOutputRecords()
 //
 // ** Encoding Functions **
 // - EncodeInputMap()
@@ -23,6 +40,13 @@ import (
 // - ASCIIEscapeUnicode()
 // - DehexMap()
 // - HexEncodeMap()
+// - EncodeOutputMap()
+// - EncodeOutputString()
+//
+// ** Markov Functions **
+// - CreateMarkovMap()
+// - FormatMarkovByte()
+// - SaveMarkovToHcstat2()
 //
 // ----------------------------------------------------------------------------
 // Functions without Unit Tests
@@ -32,6 +56,9 @@ import (
 // - PrintStatsToSTDOUT() (Output Functions)
 // - CreateVerboseStats() (Output Functions)
 // - SaveArrayToJSON() (Output Functions)
+// - SaveProvenance() (Output Functions)
+// - SaveOutputRecords() (Output Functions)
+// - GetOutputEncoder() (Encoding Functions)
 //
 
 // Unit Test for StatClassifyToken()
@@ -154,6 +181,35 @@ func TestRemoveLengthRange(t *testing.T) {
 	}
 }
 
+// Unit Test for RemoveMinimumEntropy()
+func TestRemoveMinimumEntropy(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input      map[string]int
+		min        float64
+		dictionary map[string]int
+		output     map[string]int
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{map[string]int{"password": 1, "Summer24": 2, "?l?l?l?d?d?d": 3}, 30, nil, map[string]int{"password": 1, "Summer24": 2}},
+		{map[string]int{"password": 1, "Summer24": 2, "?l?l?l?d?d?d": 3}, 40, nil, map[string]int{"Summer24": 2}},
+		{map[string]int{"password": 1, "Summer24": 2, "?l?l?l?d?d?d": 3}, 20, map[string]int{"password": 1, "summer": 1}, map[string]int{"Summer24": 2, "?l?l?l?d?d?d": 3}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := RemoveMinimumEntropy(test.input, test.min, test.dictionary)
+		if utils.CheckAreMapsEqual(result, test.output) == false {
+			t.Errorf("RemoveMinimumEntropy() failed - expected: %v, got: %v", test.output, result)
+		}
+	}
+}
+
 // Unit Test for FilterTopN()
 func TestFilterTopN(t *testing.T) {
 
@@ -183,6 +239,263 @@ func TestFilterTopN(t *testing.T) {
 	}
 }
 
+// Unit Test for CreateCredentialStats()
+func TestCreateCredentialStats(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		credentials []models.Credential
+		maxItems    int
+		output      string
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{
+			[]models.Credential{
+				{Username: "jsmith", NTHash: "a", Plaintext: "Summer2024!", Cracked: true},
+				{Username: "adoe", NTHash: "b", Plaintext: "Summer2024!", Cracked: true},
+				{Username: "svc_backup", NTHash: "c", Plaintext: "svc_backup", Cracked: true},
+				{Username: "Admin", NTHash: "d", Plaintext: "admin123", Cracked: true},
+				{Username: "bo", NTHash: "e", Plaintext: "bo2024", Cracked: true},
+				{Username: "krbtgt", NTHash: "f"},
+				{Username: "Guest", NTHash: "31D6CFE0D16AE931B73C59D7E0C089C0", Cracked: true},
+				{Username: "disabled", NTHash: EmptyNTHash},
+			},
+			0,
+			"Credential Stats:\nTotal Accounts: 8\nCracked Accounts: 5 (62.50%)\nEmpty Passwords: 2\nUnique Passwords: 4\nPassword Matches Username: 1\nPassword Contains Username: 1\nShared Passwords: 1\n\nShared Passwords:\nSummer2024! [2]: adoe, jsmith\n\nPasswords Based on Username:\nsvc_backup: [username]\nAdmin: admin123\n",
+		},
+		{
+			[]models.Credential{
+				{Username: "a1", NTHash: "a", Plaintext: "Winter1", Cracked: true},
+				{Username: "a2", NTHash: "a", Plaintext: "Winter1", Cracked: true},
+				{Username: "b1", NTHash: "b", Plaintext: "Spring1", Cracked: true},
+				{Username: "b2", NTHash: "b", Plaintext: "Spring1", Cracked: true},
+				{Username: "b3", NTHash: "b", Plaintext: "Spring1", Cracked: true},
+			},
+			1,
+			"Credential Stats:\nTotal Accounts: 5\nCracked Accounts: 5 (100.00%)\nEmpty Passwords: 0\nUnique Passwords: 2\nPassword Matches Username: 0\nPassword Contains Username: 0\nShared Passwords: 2\n\nShared Passwords:\nSpring1 [3]: b1, b2, b3\n",
+		},
+		{nil, 0, "Credential Stats:\nTotal Accounts: 0\nCracked Accounts: 0 (0.00%)\nEmpty Passwords: 0\nUnique Passwords: 0\nPassword Matches Username: 0\nPassword Contains Username: 0\nShared Passwords: 0\n"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := CreateCredentialStats(test.credentials, test.maxItems)
+		if result != test.output {
+			t.Errorf("CreateCredentialStats() failed - expected: %q, got: %q", test.output, result)
+		}
+	}
+}
+
+// Unit Test for CreateProvenanceRecords()
+func TestCreateProvenanceRecords(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		freq       map[string]int
+		provenance map[string][]string
+		output     []models.ProvenanceRecord
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{
+			map[string]int{"falcon": 2, "acme": 2, "winter": 5},
+			map[string][]string{"falcon": {"b.txt", "a.txt"}, "acme": {"stdin"}, "winter": {"https://example.com"}, "removed": {"a.txt"}},
+			[]models.ProvenanceRecord{
+				{Item: "winter", Count: 5, Sources: []string{"https://example.com"}},
+				{Item: "acme", Count: 2, Sources: []string{"stdin"}},
+				{Item: "falcon", Count: 2, Sources: []string{"a.txt", "b.txt"}},
+			},
+		},
+		{map[string]int{"unknown": 1}, map[string][]string{}, []models.ProvenanceRecord{{Item: "unknown", Count: 1, Sources: []string{}}}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := CreateProvenanceRecords(test.freq, test.provenance)
+		if !reflect.DeepEqual(result, test.output) {
+			t.Errorf("CreateProvenanceRecords() failed - expected: %v, got: %v", test.output, result)
+		}
+	}
+}
+
+// Unit Test for ParseOutputFields()
+func TestParseOutputFields(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  string
+		output []string
+		err    bool
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{"", nil, false},
+		{"source, MASK", []string{"mask", "source"}, false},
+		{"length,all", OutputFields, false},
+		{"mask,bogus", nil, true},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result, err := ParseOutputFields(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("ParseOutputFields(%q) failed - expected error: %v, got: %v", test.input, test.err, err)
+		} else if err == nil && !reflect.DeepEqual(result, test.output) {
+			t.Errorf("ParseOutputFields(%q) failed - expected: %v, got: %v", test.input, test.output, result)
+		}
+	}
+}
+
+// Unit Test for CreateOutputRecord()
+func TestCreateOutputRecord(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		item    string
+		count   int
+		options models.OutputOptions
+		output  models.OutputRecord
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	provenance := map[string][]string{"Winter1": {"b.txt", "a.txt"}}
+	tests := testCases{
+		{"Winter1", 3, models.OutputOptions{}, models.OutputRecord{Item: "Winter1", Count: 3}},
+		{"Winter1", 3, models.OutputOptions{Fields: []string{"mask", "length"}, ReplacementMask: "uld"}, models.OutputRecord{Item: "Winter1", Count: 3, Mask: "?u?l?l?l?l?l?d", Length: 7}},
+		{"Winter1", 3, models.OutputOptions{Fields: []string{"source"}, Provenance: provenance}, models.OutputRecord{Item: "Winter1", Count: 3, Sources: []string{"a.txt", "b.txt"}}},
+		{"Winter1", 3, models.OutputOptions{Fields: []string{"hashtype"}, HashTypes: map[string][]string{"Winter1": {"ntlm", "md5"}}}, models.OutputRecord{Item: "Winter1", Count: 3, HashTypes: []string{"md5", "ntlm"}}},
+		{"abc", 1, models.OutputOptions{Fields: []string{"category"}}, models.OutputRecord{Item: "abc", Count: 1, Category: []string{"all-lowercase", "alphabetical", "non-complex", "short-non-complex"}}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := CreateOutputRecord(test.item, test.count, test.options)
+		if !reflect.DeepEqual(result, test.output) {
+			t.Errorf("CreateOutputRecord(%q) failed - expected: %v, got: %v", test.item, test.output, result)
+		}
+	}
+}
+
+// Unit Test for WriteOutputRecords()
+func TestWriteOutputRecords(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		freq    map[string]int
+		options models.OutputOptions
+		output  string
+		err     bool
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	freq := map[string]int{"acme": 2, "winter": 5, "falcon": 2}
+	provenance := map[string][]string{"winter": {"a.txt", "b.txt"}, "acme": {"a.txt"}}
+	tests := testCases{
+		{freq, models.OutputOptions{Format: "jsonl"}, "{\"item\":\"winter\",\"count\":5}\n{\"item\":\"acme\",\"count\":2}\n{\"item\":\"falcon\",\"count\":2}\n", false},
+		{freq, models.OutputOptions{Format: "csv"}, "item,count\nwinter,5\nacme,2\nfalcon,2\n", false},
+		{freq, models.OutputOptions{Format: "csv", Fields: []string{"length", "source"}, Provenance: provenance}, "item,count,length,sources\nwinter,5,6,a.txt;b.txt\nacme,2,4,a.txt\nfalcon,2,6,\n", false},
+		{map[string]int{"acme": 1}, models.OutputOptions{Format: "csv", Fields: []string{"hashtype"}, HashTypes: map[string][]string{"acme": {"md5", "ntlm"}}}, "item,count,hashtypes\nacme,1,md5;ntlm\n", false},
+		{map[string]int{"a,\"b\"": 1}, models.OutputOptions{Format: "csv"}, "item,count\n\"a,\"\"b\"\"\",1\n", false},
+		{map[string]int{"<a&b>": 1, "caf\xe9": 1}, models.OutputOptions{Format: "jsonl"}, "{\"item\":\"<a&b>\",\"count\":1}\n{\"item\":\"$HEX[636166e9]\",\"count\":1}\n", false},
+		{map[string]int{"café": 1}, models.OutputOptions{Format: "csv", Encoding: "latin1"}, "item,count\ncaf\xe9,1\n", false},
+		{map[string]int{"$HEX[6869]": 1}, models.OutputOptions{Format: "jsonl"}, "{\"item\":\"$HEX[244845585b363836395d]\",\"count\":1}\n", false},
+		{freq, models.OutputOptions{Format: "xml"}, "", true},
+		{freq, models.OutputOptions{Format: "csv", Encoding: "bogus"}, "", true},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		err := WriteOutputRecords(&buffer, test.freq, test.options)
+		if (err != nil) != test.err {
+			t.Errorf("WriteOutputRecords(%v) failed - expected error: %v, got: %v", test.options, test.err, err)
+		} else if err == nil && buffer.String() != test.output {
+			t.Errorf("WriteOutputRecords(%v) failed - expected: %q, got: %q", test.options, test.output, buffer.String())
+		}
+	}
+}
+
+// Unit Test for WriteOutputRecords() read back with utils.ReadInputToMap()
+func TestWriteOutputRecordsRoundTrip(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		options       models.OutputOptions
+		inputEncoding string
+	}
+
+	type testCases []testCase
+
+	// Define a test case with items that are written in the $HEX[...] format
+	freq := map[string]int{"winter": 5, "café": 3, "caf\xe9": 2, "爱": 2, "$HEX[6869]": 1, "a,\"b\"": 1}
+	tests := testCases{
+		{models.OutputOptions{Format: "jsonl"}, ""},
+		{models.OutputOptions{Format: "jsonl", Fields: []string{"mask", "length"}, ReplacementMask: "uldsb"}, ""},
+		{models.OutputOptions{Format: "csv"}, ""},
+		{models.OutputOptions{Format: "csv", Encoding: "latin1"}, "latin1"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		if err := WriteOutputRecords(&buffer, freq, test.options); err != nil {
+			t.Fatalf("WriteOutputRecords(%v) error: %v", test.options, err)
+		}
+
+		result, err := utils.ReadInputToMap(&buffer, "records", "", utils.DefaultMaxLineLength, test.inputEncoding, false)
+		if err != nil || !utils.CheckAreMapsEqual(result, freq) {
+			t.Errorf("WriteOutputRecords(%v) read back = %q, %v; want %q", test.options, result, err, freq)
+		}
+	}
+}
+
+// Unit Test for CreateIgnoreCaseProvenance()
+func TestCreateIgnoreCaseProvenance(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  map[string][]string
+		output map[string][]string
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{map[string][]string{"Acme": {"a.txt"}}, map[string][]string{"acme": {"a.txt"}}},
+		{map[string][]string{"Acme": {"a.txt"}, "ACME": {"a.txt"}}, map[string][]string{"acme": {"a.txt"}}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := CreateIgnoreCaseProvenance(test.input)
+		if !reflect.DeepEqual(result, test.output) {
+			t.Errorf("CreateIgnoreCaseProvenance() failed - expected: %v, got: %v", test.output, result)
+		}
+	}
+
+	// Sources from keys that only differ by case are combined
+	result := CreateIgnoreCaseProvenance(map[string][]string{"Acme": {"a.txt"}, "acme": {"stdin"}})
+	sort.Strings(result["acme"])
+	if !reflect.DeepEqual(result, map[string][]string{"acme": {"a.txt", "stdin"}}) {
+		t.Errorf("CreateIgnoreCaseProvenance() failed - expected: %v, got: %v", map[string][]string{"acme": {"a.txt", "stdin"}}, result)
+	}
+}
+
 // Unit Test for EncodeInputMap()
 func TestEncodeInputMap(t *testing.T) {
 
@@ -388,3 +701,175 @@ func TestHexEncodeMap(t *testing.T) {
 		}
 	}
 }
+
+// Unit Test for EncodeOutputMap()
+func TestEncodeOutputMap(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input    map[string]int
+		encoding string
+		output   map[string]int
+		err      bool
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{map[string]int{"love": 1, "café": 2}, "hex", map[string]int{"love": 1, "café": 2}, false},
+		{map[string]int{"love": 1, "caf\xe9": 2}, "hex", map[string]int{"love": 1, "$HEX[636166e9]": 2}, false},
+		{map[string]int{"love": 1, "café": 2, "爱": 3}, "latin1", map[string]int{"love": 1, "caf\xe9": 2, "$HEX[e788b1]": 3}, false},
+		{map[string]int{"愛してる": 1}, "shift_jis", map[string]int{"\x88\xa4\x82\xb5\x82\xc4\x82\xe9": 1}, false},
+		{map[string]int{"love": 1}, "bogus", nil, true},
+		{map[string]int{"love": 1}, "utf-16le", nil, true},
+		{map[string]int{"love": 1}, "UTF-16BE", nil, true},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result, err := EncodeOutputMap(test.input, test.encoding)
+		if (err != nil) != test.err {
+			t.Errorf("EncodeOutputMap() failed - expected error: %v, got: %v", test.err, err)
+		} else if err == nil && utils.CheckAreMapsEqual(result, test.output) == false {
+			t.Errorf("EncodeOutputMap() failed - expected: %v, got: %v", test.output, result)
+		}
+	}
+}
+
+// Unit Test for EncodeOutputString()
+func TestEncodeOutputString(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input    string
+		encoding string
+		output   string
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{"café", "hex", "café"},
+		{"caf\xe9", "hex", "$HEX[636166e9]"},
+		{"café", "latin1", "caf\xe9"},
+		{"爱", "latin1", "$HEX[e788b1]"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		encoder, err := GetOutputEncoder(test.encoding)
+		if err != nil {
+			t.Fatalf("GetOutputEncoder(%q) failed - unexpected error: %v", test.encoding, err)
+		}
+		result := EncodeOutputString(test.input, encoder)
+		if result != test.output {
+			t.Errorf("EncodeOutputString(%q, %q) failed - expected: %q, got: %q", test.input, test.encoding, test.output, result)
+		}
+	}
+}
+
+// Unit Test for CreateMarkovMap()
+func TestCreateMarkovMap(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  map[string]int
+		output map[string]int
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{map[string]int{"ab": 1}, map[string]int{"0:a": 1, "1:b": 1, "0:ab": 1}},
+		{map[string]int{"abc": 2, "abd": 3}, map[string]int{"0:a": 5, "1:b": 5, "2:c": 2, "2:d": 3, "0:ab": 5, "1:bc": 2, "1:bd": 3}},
+		{map[string]int{"爱": 1}, map[string]int{"0:\\xE7": 1, "1:\\x88": 1, "2:\\xB1": 1, "0:\\xE7\\x88": 1, "1:\\x88\\xB1": 1}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := CreateMarkovMap(test.input, false, false)
+		if utils.CheckAreMapsEqual(result, test.output) == false {
+			t.Errorf("CreateMarkovMap() failed - expected: %v, got: %v", test.output, result)
+		}
+	}
+}
+
+// Unit Test for FormatMarkovByte()
+func TestFormatMarkovByte(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  byte
+		output string
+	}
+
+	type testCases []testCase
+
+	// Define a test case
+	tests := testCases{
+		{'a', "a"},
+		{' ', " "},
+		{'~', "~"},
+		{'\t', "\\x09"},
+		{0xE7, "\\xE7"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		result := FormatMarkovByte(test.input)
+		if result != test.output {
+			t.Errorf("FormatMarkovByte() failed - expected: %v, got: %v", test.output, result)
+		}
+	}
+}
+
+// Unit Test for SaveMarkovToHcstat2()
+func TestSaveMarkovToHcstat2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.hcstat2")
+	if err := SaveMarkovToHcstat2(path, map[string]int{"ab": 3, "a": 2}); err != nil {
+		t.Fatalf("SaveMarkovToHcstat2() failed - unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("SaveMarkovToHcstat2() failed - could not open file: %v", err)
+	}
+	defer file.Close()
+
+	reader, err := lzma.NewReader2(file)
+	if err != nil {
+		t.Fatalf("SaveMarkovToHcstat2() failed - could not decompress file: %v", err)
+	}
+
+	// Read the header and root table then count the Markov table
+	rootSize := MarkovMaxLength * MarkovCharsetSize
+	head := make([]byte, 8*(2+rootSize)+8*int('a')*MarkovCharsetSize+8*int('b')+8)
+	if _, err := io.ReadFull(reader, head); err != nil {
+		t.Fatalf("SaveMarkovToHcstat2() failed - could not read tables: %v", err)
+	}
+	rest, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		t.Fatalf("SaveMarkovToHcstat2() failed - could not read tables: %v", err)
+	}
+
+	markovSize := MarkovMaxLength * MarkovCharsetSize * MarkovCharsetSize
+	if size := int64(len(head)) + rest; size != int64(8*(2+rootSize+markovSize)) {
+		t.Errorf("SaveMarkovToHcstat2() failed - expected size: %d, got: %d", 8*(2+rootSize+markovSize), size)
+	}
+
+	values := map[string][2]uint64{
+		"version":   {binary.BigEndian.Uint64(head[0:8]), MarkovHcstat2Version},
+		"padding":   {binary.BigEndian.Uint64(head[8:16]), 0},
+		"root a":    {binary.BigEndian.Uint64(head[16+8*int('a'):]), 5},
+		"root 1 b":  {binary.BigEndian.Uint64(head[16+8*(MarkovCharsetSize+int('b')):]), 3},
+		"markov ab": {binary.BigEndian.Uint64(head[len(head)-8:]), 3},
+	}
+	for name, value := range values {
+		if value[0] != value[1] {
+			t.Errorf("SaveMarkovToHcstat2() failed - expected %s: %d, got: %d", name, value[1], value[0])
+		}
+	}
+}
@@ -2,12 +2,17 @@
 package mask
 
 import (
+	"encoding/hex"
 	"fmt"
+	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/jakewnuk/ptt/pkg/models"
 	"github.com/jakewnuk/ptt/pkg/utils"
 )
 
@@ -89,12 +94,14 @@ func MakeMaskedMap(input map[string]int, replacementMask string, verbose bool, b
 	for key, value := range input {
 		newKey := replacer.Replace(key)
 
-		if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "b") {
+		if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "r") {
+			newKey = ConvertUnicodeMask(newKey, replacementMask)
+		} else if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "b") {
 			newKey = ConvertMultiByteMask(newKey)
 		}
 
 		if verbose {
-			newKey = fmt.Sprintf("%s:%d:%d:%d", newKey, len(key), TestMaskComplexity(newKey), CalculateMaskKeyspace(newKey))
+			newKey = fmt.Sprintf("%s:%d:%d:%d:%.2f", newKey, len(key), TestMaskComplexity(newKey), CalculateMaskKeyspace(newKey), CalculateEntropy(newKey, nil))
 		}
 
 		if debug {
@@ -120,8 +127,17 @@ func MakeMaskedMap(input map[string]int, replacementMask string, verbose bool, b
 	return maskedMap
 }
 
+// MaxRetainCombinationOccurrences is the maximum number of keyword occurrences
+// in an item for which every combination of occurrences is masked
+const MaxRetainCombinationOccurrences = 16
+
 // MakeRetainMaskedMap replaces all characters in the input maps key with mask
-// values in the input map but retains keywords provided in the retain list
+// values in the input map but retains keywords provided in the retain list.
+// Every occurrence of every keyword is retained with longer keywords chosen
+// first when occurrences overlap. If combinations is true, a mask is created
+// for every combination of non-overlapping keyword occurrences instead. Items
+// with more than MaxRetainCombinationOccurrences occurrences only create the
+// longest match mask since the number of combinations grows exponentially.
 //
 // Args:
 //
@@ -131,58 +147,76 @@ func MakeMaskedMap(input map[string]int, replacementMask string, verbose bool, b
 //	bypass (bool): If true, the map is not used for output or filtering
 //	debug (bool): If true, print additional debug information to stderr
 //	verbose (bool): Verbose information if true
+//	combinations (bool): If true, create masks for every combination of
+//	keyword occurrences
 //
 // Returns:
 //
 //	maskedMap (map[string]int): Masked retain map
-func MakeRetainMaskedMap(input map[string]int, replacementMask string, retain map[string]int, bypass bool, debug bool, verbose bool) map[string]int {
+func MakeRetainMaskedMap(input map[string]int, replacementMask string, retain map[string]int, bypass bool, debug bool, verbose bool, combinations bool) map[string]int {
 	maskedMap := make(map[string]int)
 	replacements := ConstructReplacements(replacementMask)
 	replacer := strings.NewReplacer(replacements...)
+	index := NewRetainIndex(retain)
+
+	maskPart := func(part string) string {
+		newPart := replacer.Replace(part)
+		if !utils.CheckASCIIString(newPart) && strings.Contains(replacementMask, "r") {
+			newPart = ConvertUnicodeMask(newPart, replacementMask)
+		} else if !utils.CheckASCIIString(newPart) && strings.Contains(replacementMask, "b") {
+			newPart = ConvertMultiByteMask(newPart)
+		}
+		return newPart
+	}
 
 	for key, value := range input {
-		for retainKey := range retain {
+		occurrences := FindRetainOccurrences(key, index)
+		if len(occurrences) == 0 {
+			continue
+		}
+
+		// Too many occurrences would create an unbounded number of
+		// combinations so those fall back to the longest match
+		var selections [][][2]int
+		if combinations && len(occurrences) <= MaxRetainCombinationOccurrences {
+			selections = CombineRetainOccurrences(occurrences)
+		} else {
+			if combinations && debug {
+				fmt.Fprintf(os.Stderr, "[?] MakeRetainMaskedMap: %s has %d keyword occurrences which is more than %d. Only the longest match mask is created.\n", key, len(occurrences), MaxRetainCombinationOccurrences)
+			}
+			selections = [][][2]int{SelectLongestRetainOccurrences(occurrences)}
+		}
+
+		seen := make(map[string]bool)
+		for _, selection := range selections {
 			newKey := ""
-			if strings.Contains(key, retainKey) {
-				parts := utils.SplitBySeparatorString(key, retainKey)
-
-				// if the part is not the key replace it using replacer
-				for _, part := range parts {
-					if part != retainKey {
-						newPart := replacer.Replace(part)
-						if !utils.CheckASCIIString(newPart) && strings.Contains(replacementMask, "b") {
-							newPart = ConvertMultiByteMask(newPart)
-						}
-						newKey += newPart
-					} else {
-						newKey += part
-					}
-				}
+			last := 0
+			for _, occurrence := range selection {
+				newKey += maskPart(key[last:occurrence[0]]) + key[occurrence[0]:occurrence[1]]
+				last = occurrence[1]
+			}
+			newKey += maskPart(key[last:])
 
-			} else {
-				// if the key is not in the string continue
+			if seen[newKey] {
 				continue
 			}
+			seen[newKey] = true
 
 			if verbose {
-				newKey = fmt.Sprintf("%s:%d:%d:%d", newKey, len(key), TestMaskComplexity(newKey), CalculateMaskKeyspace(newKey))
+				newKey = fmt.Sprintf("%s:%d:%d:%d:%.2f", newKey, len(key), TestMaskComplexity(newKey), CalculateMaskKeyspace(newKey), CalculateEntropy(newKey, index))
 			}
 
 			if debug {
 				fmt.Fprintf(os.Stderr, "[?] MakeRetainMaskedMap:\n")
 				fmt.Fprintf(os.Stderr, "Key: %s\n", key)
-				fmt.Fprintf(os.Stderr, "Retain Key: %s\n", retainKey)
+				fmt.Fprintf(os.Stderr, "Retained: %v\n", selection)
 				fmt.Fprintf(os.Stderr, "New Key: %s\n", newKey)
 				fmt.Fprintf(os.Stderr, "Replacement Mask: %s\n", replacementMask)
 			}
 
 			switch bypass {
 			case false:
-				if oldValue, exists := maskedMap[newKey]; exists {
-					maskedMap[newKey] = oldValue + value
-				} else {
-					maskedMap[newKey] = value
-				}
+				maskedMap[newKey] += value
 			case true:
 				fmt.Println(newKey)
 			}
@@ -207,13 +241,110 @@ func MakeMaskedString(input string, replacementMask string) string {
 	replacer := strings.NewReplacer(replacements...)
 	newKey := replacer.Replace(input)
 
-	if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "b") {
+	if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "r") {
+		newKey = ConvertUnicodeMask(newKey, replacementMask)
+	} else if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "b") {
 		newKey = ConvertMultiByteMask(newKey)
 	}
 
 	return newKey
 }
 
+// MakeHexCharsetMaskedMap replaces all characters in the input maps key with
+// a mask for use with the hashcat --hex-charset option
+//
+// Args:
+//
+//	input (map[string]int): Map to mask
+//	replacementMask (string): Mask characters to apply
+//	bypass (bool): If true, the map is not used for output or filtering
+//	debug (bool): If true, print additional debug information to stderr
+//
+// Returns:
+//
+//	maskedMap (map[string]int): Masked map in hcmask format
+func MakeHexCharsetMaskedMap(input map[string]int, replacementMask string, bypass bool, debug bool) map[string]int {
+	maskedMap := make(map[string]int)
+
+	for key, value := range input {
+		newKey := MakeHexCharsetMask(key, replacementMask)
+
+		if debug {
+			fmt.Fprintf(os.Stderr, "[?] MakeHexCharsetMaskedMap:\n")
+			fmt.Fprintf(os.Stderr, "Key: %s\n", key)
+			fmt.Fprintf(os.Stderr, "New Key: %s\n", newKey)
+			fmt.Fprintf(os.Stderr, "Replacement Mask: %s\n", replacementMask)
+		}
+
+		switch bypass {
+		case false:
+			maskedMap[newKey] += value
+		case true:
+			fmt.Println(newKey)
+		}
+	}
+	return maskedMap
+}
+
+// MakeHexCharsetMask replaces all characters in the input string with the
+// mask values in the mask string and writes every other character as hex so
+// the mask can be used with the hashcat --hex-charset option. Masked
+// multibyte characters keep their UTF-8 lead byte and use the ?1 custom
+// charset for each continuation byte. If ?1 is used, the charset is
+// prepended to the mask in hcmask format.
+//
+// Args:
+//
+//	input (string): Input string
+//	replacementMask (string): Mask characters to apply
+//
+// Returns:
+//
+//	(string): Hex charset mask
+func MakeHexCharsetMask(input string, replacementMask string) string {
+	replacements := ConstructReplacements(replacementMask)
+	replacer := strings.NewReplacer(replacements...)
+	newKey := ""
+	usesCharset := false
+
+	for _, r := range input {
+		char := string(r)
+
+		if r <= 127 {
+			if masked := replacer.Replace(char); masked != char {
+				newKey += masked
+			} else {
+				newKey += hex.EncodeToString([]byte(char))
+			}
+			continue
+		}
+
+		masked := strings.Contains(replacementMask, "b")
+		if strings.Contains(replacementMask, "r") && ConvertUnicodeMask(char, replacementMask) != char {
+			masked = true
+		}
+
+		if !masked {
+			newKey += hex.EncodeToString([]byte(char))
+			continue
+		}
+
+		newKey += hex.EncodeToString([]byte(char)[:1])
+		newKey += strings.Repeat("?1", utf8.RuneLen(r)-1)
+		usesCharset = true
+	}
+
+	if usesCharset {
+		charset := ""
+		for b := 0x80; b <= 0xbf; b++ {
+			charset += fmt.Sprintf("%02x", b)
+		}
+		newKey = charset + "," + newKey
+	}
+
+	return newKey
+}
+
 // ----------------------------------------------------------------------------
 // Mask Conversion Functions
 // ----------------------------------------------------------------------------
@@ -246,6 +377,44 @@ func ConvertMultiByteMask(str string) string {
 	return returnStr
 }
 
+// ConvertUnicodeMask converts non-ascii characters to a valid format by
+// classifying each rune instead of each byte. Uppercase, lowercase, and
+// numerical runes are converted to ?u, ?l, and ?d when those characters are
+// in the replacement mask. Runes without a matching class are converted to
+// ?b per byte if "b" is in the replacement mask and are kept otherwise.
+//
+// Args:
+//
+//	str (string): Input string
+//	replacementMask (string): Mask characters to apply
+//
+// Returns:
+//
+//	returnStr (string): Converted string
+func ConvertUnicodeMask(str string, replacementMask string) string {
+	returnStr := ""
+	for _, r := range str {
+		if r <= 127 {
+			returnStr += string(r)
+			continue
+		}
+
+		switch {
+		case unicode.IsUpper(r) && strings.Contains(replacementMask, "u"):
+			returnStr += "?u"
+		case unicode.IsLower(r) && strings.Contains(replacementMask, "l"):
+			returnStr += "?l"
+		case unicode.IsDigit(r) && strings.Contains(replacementMask, "d"):
+			returnStr += "?d"
+		case strings.Contains(replacementMask, "b"):
+			returnStr += strings.Repeat("?b", utf8.RuneLen(r))
+		default:
+			returnStr += string(r)
+		}
+	}
+	return returnStr
+}
+
 // TestMaskComplexity tests the complexity of an input full mask or a partial
 // mask string and returns a score
 //
@@ -315,6 +484,112 @@ func TestMaskComplexity(str string) int {
 	return score
 }
 
+// CalculateEntropy calculates an entropy score in bits for an input string,
+// full mask, or partial mask. Mask characters add the size of their charset
+// and literal characters add their length times the combined size of the
+// charsets they use. Tokens found in the dictionary are scored as a single
+// choice from the dictionary instead of by character with a floor of one bit
+// per token so a dictionary of one token does not score its token as zero.
+//
+// Args:
+//
+//	str (string): Input string, full mask, or partial mask
+//	dictionary (*models.RetainIndex): Index of dictionary tokens to adjust
+//	for or nil
+//
+// Returns:
+//
+//	(float64): Entropy score in bits
+func CalculateEntropy(str string, dictionary *models.RetainIndex) float64 {
+	entropy := 0.0
+	remaining := str
+
+	if dictionary != nil && len(dictionary.Keywords) > 0 {
+		occurrences := SelectLongestRetainOccurrences(FindRetainOccurrences(str, dictionary))
+		entropy += float64(len(occurrences)) * max(1, math.Log2(float64(len(dictionary.Keywords))))
+
+		// Tokens are replaced with a newline so mask characters can not be
+		// joined across them
+		remaining = ""
+		last := 0
+		for _, occurrence := range occurrences {
+			remaining += str[last:occurrence[0]] + "\n"
+			last = occurrence[1]
+		}
+		remaining += str[last:]
+	}
+
+	literals := 0
+	lowerBool, upperBool, digitBool, specialBool, byteBool := false, false, false, false, false
+	for i := 0; i < len(remaining); {
+		if remaining[i] == '\n' {
+			i++
+			continue
+		}
+
+		if remaining[i] == '?' && i+1 < len(remaining) {
+			size := 0
+			switch remaining[i+1] {
+			case 'l', 'u':
+				size = 26
+			case 'd':
+				size = 10
+			case 's':
+				size = 33
+			case 'a':
+				size = 95
+			case 'b':
+				size = 256
+			case 'h', 'H':
+				size = 16
+			}
+			if size > 0 {
+				entropy += math.Log2(float64(size))
+				i += 2
+				continue
+			}
+		}
+
+		r, width := utf8.DecodeRuneInString(remaining[i:])
+		switch {
+		case r >= 'a' && r <= 'z':
+			lowerBool = true
+		case r >= 'A' && r <= 'Z':
+			upperBool = true
+		case r >= '0' && r <= '9':
+			digitBool = true
+		case r <= 127:
+			specialBool = true
+		default:
+			byteBool = true
+		}
+		literals++
+		i += width
+	}
+
+	charset := 0
+	if lowerBool {
+		charset += 26
+	}
+	if upperBool {
+		charset += 26
+	}
+	if digitBool {
+		charset += 10
+	}
+	if specialBool {
+		charset += 33
+	}
+	if byteBool {
+		charset += 256
+	}
+	if literals > 0 {
+		entropy += float64(literals) * math.Log2(float64(charset))
+	}
+
+	return entropy
+}
+
 // RemoveMaskedCharacters removes masked characters from the input map
 // and returns a new map
 //
@@ -365,55 +640,317 @@ func RemoveMaskedCharacters(input map[string]int, replacementMask string, bypass
 
 // MakeMatchedMaskedMap returns a map from the input map where the keys matched
 // the keys in the mask map after applying the mask to the input map. The
-// original keys and values are retained.
+// original keys and values are retained. Keys in the mask map can also be
+// partial masks containing literal characters, masks using the '*' wildcard
+// for any number of characters, or regular expressions prefixed with
+// "regex:". If verbose is true, the matched mask is appended to the key.
 //
 // Args:
 //
 //	input (map[string]int): Input map
 //	replacementMask (string): Mask characters to apply
 //	maskMap (map[string]int): Mask map
+//	verbose (bool): If true, the matched mask is appended to the output
 //	bypass (bool): If true, the map is not used for output or filtering
 //	debug (bool): If true, print additional debug information to stderr
 //
 // Returns:
 // (map[string]int): Matched masked map
-func MakeMatchedMaskedMap(input map[string]int, replacementMask string, maskMap map[string]int, bypass bool, debug bool) map[string]int {
+func MakeMatchedMaskedMap(input map[string]int, replacementMask string, maskMap map[string]int, verbose bool, bypass bool, debug bool) map[string]int {
 	maskedMap := make(map[string]int)
 	replacements := ConstructReplacements(replacementMask)
 	replacer := strings.NewReplacer(replacements...)
 
+	// Full masks made of replacement mask tokens are matched by lookup so
+	// only the remaining patterns are tested against every key
+	var partialMasks []string
+	regexMasks := make(map[string]*regexp.Regexp)
+	for pattern := range maskMap {
+		if strings.HasPrefix(pattern, "regex:") {
+			re, err := regexp.Compile(strings.TrimPrefix(pattern, "regex:"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error compiling regular expression %s: %s.\n", pattern, err)
+				continue
+			}
+			regexMasks[pattern] = re
+		} else if !IsMaskInReplacementMask(pattern, replacementMask) {
+			partialMasks = append(partialMasks, pattern)
+		}
+	}
+
 	for key, value := range input {
 		newKey := replacer.Replace(key)
 
-		if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "b") {
+		if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "r") {
+			newKey = ConvertUnicodeMask(newKey, replacementMask)
+		} else if !utils.CheckASCIIString(newKey) && strings.Contains(replacementMask, "b") {
 			newKey = ConvertMultiByteMask(newKey)
 		}
 
+		var matches []string
+		if _, exists := maskMap[newKey]; exists {
+			matches = append(matches, newKey)
+		}
+
+		for _, pattern := range partialMasks {
+			if pattern != newKey && MatchMaskPattern(key, pattern) {
+				matches = append(matches, pattern)
+			}
+		}
+
+		for pattern, re := range regexMasks {
+			if re.MatchString(key) {
+				matches = append(matches, pattern)
+			}
+		}
+
 		if debug {
 			fmt.Fprintf(os.Stderr, "[?] MakeMatchedMaskedMap:\n")
 			fmt.Fprintf(os.Stderr, "Key: %s\n", key)
 			fmt.Fprintf(os.Stderr, "New Key: %s\n", newKey)
 			fmt.Fprintf(os.Stderr, "Replacement Mask: %s\n", replacementMask)
+			fmt.Fprintf(os.Stderr, "Matches: %v\n", matches)
 		}
 
-		switch bypass {
-		case false:
-			if _, exists := maskMap[newKey]; exists {
-				if oldValue, exists := maskedMap[newKey]; exists {
-					maskedMap[key] = oldValue + value
-				} else {
-					maskedMap[key] = value
-				}
+		if len(matches) == 0 {
+			continue
+		}
+
+		outputKeys := []string{key}
+		if verbose {
+			outputKeys = nil
+			for _, match := range matches {
+				outputKeys = append(outputKeys, fmt.Sprintf("%s:%s", key, match))
 			}
-		case true:
-			if _, exists := maskMap[newKey]; exists {
-				fmt.Println(key)
+		}
+
+		for _, outputKey := range outputKeys {
+			switch bypass {
+			case false:
+				maskedMap[outputKey] += value
+			case true:
+				fmt.Println(outputKey)
 			}
 		}
 	}
 	return maskedMap
 }
 
+// MatchMaskPattern checks if a string matches a full or partial mask
+// pattern. The pattern is matched by byte and supports the ?u, ?l, ?d, ?s,
+// ?a, ?b, ?h, and ?H charsets, '??' for a literal '?', and '*' for any
+// number of characters. All other characters are matched literally.
+//
+// Args:
+//
+//	str (string): Input string
+//	pattern (string): Mask pattern to match
+//
+// Returns:
+//
+//	(bool): True if the string matches the pattern
+func MatchMaskPattern(str string, pattern string) bool {
+	var tokens []string
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '?' && i+1 < len(pattern) {
+			if pattern[i+1] == '?' {
+				tokens = append(tokens, "?")
+			} else {
+				tokens = append(tokens, pattern[i:i+2])
+			}
+			i++
+		} else {
+			tokens = append(tokens, pattern[i:i+1])
+		}
+	}
+
+	// Match tokens against bytes and backtrack to the last wildcard
+	s, t := 0, 0
+	wildcardToken, wildcardStr := -1, 0
+	for s < len(str) {
+		if t < len(tokens) && tokens[t] == "*" {
+			wildcardToken, wildcardStr = t, s
+			t++
+		} else if t < len(tokens) && MatchMaskToken(str[s], tokens[t]) {
+			s++
+			t++
+		} else if wildcardToken != -1 {
+			wildcardStr++
+			s, t = wildcardStr, wildcardToken+1
+		} else {
+			return false
+		}
+	}
+
+	for t < len(tokens) && tokens[t] == "*" {
+		t++
+	}
+	return t == len(tokens)
+}
+
+// MatchMaskToken checks if a single byte matches a mask token. Tokens are
+// either a mask charset like ?u or a single literal character.
+//
+// Args:
+//
+//	c (byte): Input byte
+//	token (string): Mask token to match
+//
+// Returns:
+//
+//	(bool): True if the byte matches the token
+func MatchMaskToken(c byte, token string) bool {
+	if len(token) == 1 {
+		return c == token[0]
+	}
+
+	switch token {
+	case "?u":
+		return c >= 'A' && c <= 'Z'
+	case "?l":
+		return c >= 'a' && c <= 'z'
+	case "?d":
+		return c >= '0' && c <= '9'
+	case "?h":
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')
+	case "?H":
+		return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'F')
+	case "?s":
+		return strings.IndexByte(" !\"#$%&\\()*+,-./:;<=>?@[\\]^_`{|}~'", c) >= 0
+	case "?a":
+		return c >= ' ' && c <= '~'
+	case "?b":
+		return true
+	}
+	return false
+}
+
+// NewRetainIndex indexes the keywords of a retain map by their byte length
+// so FindRetainOccurrences only looks up the substrings of each length
+// instead of searching for every keyword
+//
+// Args:
+//
+//	retain (map[string]int): Map of keywords to index
+//
+// Returns:
+//
+//	(*models.RetainIndex): Index of the keywords
+func NewRetainIndex(retain map[string]int) *models.RetainIndex {
+	index := &models.RetainIndex{Keywords: make(map[string]bool, len(retain))}
+	seen := make(map[int]bool)
+	for retainKey := range retain {
+		if retainKey == "" {
+			continue
+		}
+
+		index.Keywords[retainKey] = true
+		if !seen[len(retainKey)] {
+			seen[len(retainKey)] = true
+			index.Lengths = append(index.Lengths, len(retainKey))
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(index.Lengths)))
+	return index
+}
+
+// FindRetainOccurrences finds every occurrence of every indexed keyword
+// within the input string including overlapping occurrences
+//
+// Args:
+//
+//	str (string): Input string
+//	index (*models.RetainIndex): Index of keywords to find
+//
+// Returns:
+//
+//	([][2]int): Start and end byte index of each occurrence sorted by start
+//	and then by length
+func FindRetainOccurrences(str string, index *models.RetainIndex) [][2]int {
+	var occurrences [][2]int
+	if index == nil {
+		return occurrences
+	}
+
+	// Lengths are sorted longest first so occurrences are created in order
+	for i := 0; i < len(str); i++ {
+		for _, length := range index.Lengths {
+			if i+length <= len(str) && index.Keywords[str[i:i+length]] {
+				occurrences = append(occurrences, [2]int{i, i + length})
+			}
+		}
+	}
+	return occurrences
+}
+
+// SelectLongestRetainOccurrences selects the longest keyword occurrences
+// that do not overlap. Ties are won by the occurrence that starts first.
+//
+// Args:
+//
+//	occurrences ([][2]int): Start and end byte index of each occurrence
+//
+// Returns:
+//
+//	([][2]int): Selected occurrences sorted by start
+func SelectLongestRetainOccurrences(occurrences [][2]int) [][2]int {
+	candidates := append([][2]int{}, occurrences...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		lengthI := candidates[i][1] - candidates[i][0]
+		lengthJ := candidates[j][1] - candidates[j][0]
+		if lengthI != lengthJ {
+			return lengthI > lengthJ
+		}
+		return candidates[i][0] < candidates[j][0]
+	})
+
+	var selected [][2]int
+	for _, candidate := range candidates {
+		overlaps := false
+		for _, s := range selected {
+			if candidate[0] < s[1] && s[0] < candidate[1] {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			selected = append(selected, candidate)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i][0] < selected[j][0] })
+	return selected
+}
+
+// CombineRetainOccurrences creates every combination of keyword occurrences
+// that do not overlap
+//
+// Args:
+//
+//	occurrences ([][2]int): Start and end byte index of each occurrence
+//	sorted by start
+//
+// Returns:
+//
+//	([][][2]int): Every non-empty combination of occurrences sorted by start
+func CombineRetainOccurrences(occurrences [][2]int) [][][2]int {
+	var combinations [][][2]int
+	var combine func(next int, end int, current [][2]int)
+	combine = func(next int, end int, current [][2]int) {
+		for i := next; i < len(occurrences); i++ {
+			if occurrences[i][0] < end {
+				continue
+			}
+			combination := append(append([][2]int{}, current...), occurrences[i])
+			combinations = append(combinations, combination)
+			combine(i+1, occurrences[i][1], combination)
+		}
+	}
+	combine(0, 0, nil)
+	return combinations
+}
+
 // BoundarySplitPopMap splits the index of the input map into tokens based on
 // the provided mask string provided and returns a new map with the tokens
 // as keys and the values as the values
@@ -674,3 +1211,28 @@ func IsMaskAFullMask(input string) bool {
 
 	return true
 }
+
+// IsMaskInReplacementMask checks if a mask is a full mask made only of
+// tokens produced by the replacement mask. These masks can be matched by
+// looking up the masked string while any other mask, such as one using
+// ?a, has to be matched with MatchMaskPattern
+//
+// Args:
+// input (string): Input mask or partial mask
+// replacementMask (string): Mask characters to apply
+//
+// Returns:
+// (bool): True if every token of the mask is in the replacement mask
+func IsMaskInReplacementMask(input string, replacementMask string) bool {
+	if !IsMaskAFullMask(input) {
+		return false
+	}
+
+	for i := 1; i < len(input); i += 2 {
+		if input[i] == 'a' || !strings.ContainsRune(replacementMask, rune(input[i])) {
+			return false
+		}
+	}
+
+	return true
+}
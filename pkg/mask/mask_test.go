@@ -1,6 +1,7 @@
 package mask
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -15,23 +16,34 @@ import (
 // - MakeMaskedMap()
 // - MakeRetainMaskedMap()
 // - MakeMaskedString()
+// - MakeHexCharsetMask()
 //
 // ** Mask Conversion Functions **
 // - ConvertMultiByteMask()
+// - ConvertUnicodeMask()
 // - TestMaskComplexity()
+// - CalculateEntropy()
 // - RemoveMaskedCharacters()
 //
 // ** Mask Utility Functions **
 // - MakeMatchedMaskedMap()
+// - MatchMaskPattern()
+// - NewRetainIndex()
+// - FindRetainOccurrences()
+// - SelectLongestRetainOccurrences()
+// - CombineRetainOccurrences()
 // - BoundarySplitPopMap()
 // - ShuffleMap()
 // - CalculateKeySpace()
 // - IsMaskAFullMask()
+// - IsMaskInReplacementMask()
 //
 // ----------------------------------------------------------------------------
 // Functions without Unit Tests
 // ----------------------------------------------------------------------------
 // - CalculateMaskKeyspace()
+// - MakeHexCharsetMaskedMap()
+// - MatchMaskToken()
 
 // Unit Test for ConstructReplacements()
 func TestConstructReplacements(t *testing.T) {
@@ -81,6 +93,8 @@ func TestMakeMaskedMap(t *testing.T) {
 		{map[string]int{"abc": 1, "ABC": 2, "ABCabc123!!!": 3}, "d", map[string]int{"abc": 1, "ABC": 2, "ABCabc?d?d?d!!!": 3}},
 		{map[string]int{"abc": 1, "ABC": 2, "ABCabc123!!!": 3}, "s", map[string]int{"abc": 1, "ABC": 2, "ABCabc123?s?s?s": 3}},
 		{map[string]int{"abc": 1, "ABC": 2, "ABCabc123!!!": 3}, "luds", map[string]int{"?l?l?l": 1, "?u?u?u": 2, "?u?u?u?l?l?l?d?d?d?s?s?s": 3}},
+		{map[string]int{"Müller2024": 1, "Ωmega": 2}, "uldb", map[string]int{"?u?b?b?l?l?l?l?d?d?d?d": 1, "?b?b?l?l?l?l": 2}},
+		{map[string]int{"Müller2024": 1, "Ωmega": 2}, "uldbr", map[string]int{"?u?l?l?l?l?l?d?d?d?d": 1, "?u?l?l?l?l": 2}},
 	}
 
 	// Run test cases
@@ -100,6 +114,7 @@ func TestMakeRetainMaskedMap(t *testing.T) {
 		input        map[string]int
 		replacements string
 		retain       map[string]int
+		combinations bool
 		output       map[string]int
 	}
 
@@ -107,15 +122,18 @@ func TestMakeRetainMaskedMap(t *testing.T) {
 
 	// Define test cases
 	tests := testCases{
-		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "luds", map[string]int{"abc": 1, "ABC": 1}, map[string]int{"abc?d?d?d": 1, "ABC": 2, "ABC?l?l?l?d?d?d?s?s?s": 3, "?u?u?uabc?d?d?d?s?s?s": 3}},
-		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "lud", map[string]int{"abc": 1, "ABC": 1}, map[string]int{"abc?d?d?d": 1, "ABC": 2, "ABC?l?l?l?d?d?d!!!": 3, "?u?u?uabc?d?d?d!!!": 3}},
-		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "ld", map[string]int{"123": 1}, map[string]int{"?l?l?l123": 1, "ABC?l?l?l123!!!": 3}},
-		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "luds", map[string]int{"abc": 1, "ABC": 1}, map[string]int{"abc?d?d?d": 1, "ABC": 2, "ABC?l?l?l?d?d?d?s?s?s": 3, "?u?u?uabc?d?d?d?s?s?s": 3}},
+		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "luds", map[string]int{"abc": 1, "ABC": 1}, false, map[string]int{"abc?d?d?d": 1, "ABC": 2, "ABCabc?d?d?d?s?s?s": 3}},
+		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "lud", map[string]int{"abc": 1, "ABC": 1}, false, map[string]int{"abc?d?d?d": 1, "ABC": 2, "ABCabc?d?d?d!!!": 3}},
+		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "ld", map[string]int{"123": 1}, false, map[string]int{"?l?l?l123": 1, "ABC?l?l?l123!!!": 3}},
+		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "luds", map[string]int{"abc": 1, "ABC": 1}, true, map[string]int{"abc?d?d?d": 1, "ABC": 2, "ABC?l?l?l?d?d?d?s?s?s": 3, "?u?u?uabc?d?d?d?s?s?s": 3, "ABCabc?d?d?d?s?s?s": 3}},
+		{map[string]int{"loveyoulove1": 1}, "luds", map[string]int{"love": 1, "you": 1}, false, map[string]int{"loveyoulove?d": 1}},
+		{map[string]int{"loveyoulove1": 1}, "luds", map[string]int{"love": 1, "you": 1}, true, map[string]int{"love?l?l?l?l?l?l?l?d": 1, "?l?l?l?lyou?l?l?l?l?d": 1, "?l?l?l?l?l?l?llove?d": 1, "loveyou?l?l?l?l?d": 1, "love?l?l?llove?d": 1, "?l?l?l?lyoulove?d": 1, "loveyoulove?d": 1}},
+		{map[string]int{"password1": 1}, "luds", map[string]int{"pass": 1, "password": 1, "word": 1}, false, map[string]int{"password?d": 1}},
 	}
 
 	// Run test cases
 	for _, test := range tests {
-		output := MakeRetainMaskedMap(test.input, test.replacements, test.retain, false, false, false)
+		output := MakeRetainMaskedMap(test.input, test.replacements, test.retain, false, false, false, test.combinations)
 		if !reflect.DeepEqual(output, test.output) {
 			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
 		}
@@ -152,6 +170,38 @@ func TestMakeMaskedString(t *testing.T) {
 	}
 }
 
+// Unit Test for MakeHexCharsetMask()
+func TestMakeHexCharsetMask(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  string
+		mask   string
+		output string
+	}
+
+	type testCases []testCase
+
+	charset := "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf"
+
+	// Define test cases
+	tests := testCases{
+		{"abc123", "d", "616263?d?d?d"},
+		{"Ab?1", "uls", "?u?l?s31"},
+		{"Müller2024", "uld", "?uc3bc?l?l?l?l?d?d?d?d"},
+		{"Müller2024", "uldr", charset + ",?uc3?1?l?l?l?l?d?d?d?d"},
+		{"爱test", "b", charset + ",e7?1?174657374"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := MakeHexCharsetMask(test.input, test.mask)
+		if output != test.output {
+			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
+		}
+	}
+}
+
 // Unit Test for ConvertMultiByteMask()
 func TestConvertMultiByteMask(t *testing.T) {
 
@@ -181,6 +231,37 @@ func TestConvertMultiByteMask(t *testing.T) {
 	}
 }
 
+// Unit Test for ConvertUnicodeMask()
+func TestConvertUnicodeMask(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  string
+		mask   string
+		output string
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{"Müller2024", "lr", "M?lller2024"},
+		{"Ωmega", "ur", "?umega"},
+		{"Ωmega", "lr", "Ωmega"},
+		{"Ωmega", "lbr", "?b?bmega"},
+		{"٣爱", "dbr", "?d?b?b?b"},
+		{"test", "uldbr", "test"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := ConvertUnicodeMask(test.input, test.mask)
+		if output != test.output {
+			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
+		}
+	}
+}
+
 // Unit Test for TestMaskComplexity()
 func TestTestMaskComplexity(t *testing.T) {
 
@@ -209,6 +290,39 @@ func TestTestMaskComplexity(t *testing.T) {
 	}
 }
 
+// Unit Test for CalculateEntropy()
+func TestCalculateEntropy(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input      string
+		dictionary map[string]int
+		output     string
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{"?l?l?l?d?d?d", nil, "24.07"},
+		{"password", nil, "37.60"},
+		{"Summer24", nil, "47.63"},
+		{"?uabc123", nil, "35.72"},
+		{"password1", map[string]int{"password": 1, "love": 1}, "4.32"},
+		{"password1", map[string]int{"password": 1}, "4.32"},
+		{"password", map[string]int{"password": 1}, "1.00"},
+		{"", nil, "0.00"},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := fmt.Sprintf("%.2f", CalculateEntropy(test.input, NewRetainIndex(test.dictionary)))
+		if output != test.output {
+			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
+		}
+	}
+}
+
 // Unit Test for RemoveMaskedCharacters()
 func TestRemoveMaskedCharacters(t *testing.T) {
 
@@ -252,15 +366,181 @@ func TestMakeMatchedMaskedMap(t *testing.T) {
 		{map[string]int{"abc123": 1, "ABC": 2, "ABCabc123!!!": 3}, "luds", map[string]int{"?l?l?l": 1, "?u?u?u": 1}, map[string]int{"ABC": 2}},
 		{map[string]int{"123": 1, "123456": 2, "123456789": 3}, "d", map[string]int{"?d?d?d": 1, "?d?d?d?d?d?d": 1}, map[string]int{"123": 1, "123456": 2}},
 		{map[string]int{"🙂": 1, "😀": 2, "😁": 3}, "b", map[string]int{"?b?b?b?b": 1}, map[string]int{"🙂": 1, "😀": 2, "😁": 3}},
+		{map[string]int{"BestSummer24": 1, "Summer2024": 2, "Winter24": 3}, "uldsb", map[string]int{"?u?l?l?lSummer?d?d": 1}, map[string]int{"BestSummer24": 1}},
+		{map[string]int{"BestSummer24": 1, "Summer2024": 2, "Winter24": 3}, "uldsb", map[string]int{"*Summer?d?d*": 1}, map[string]int{"BestSummer24": 1, "Summer2024": 2}},
+		{map[string]int{"BestSummer24": 1, "Summer2024": 2, "Winter24": 3}, "uldsb", map[string]int{`regex:^[A-Z]\w+[a-z]24$`: 1}, map[string]int{"BestSummer24": 1, "Winter24": 3}},
+		{map[string]int{"abc": 1, "ab": 2, "abcd": 3}, "uldsb", map[string]int{"?a?a?a": 1}, map[string]int{"abc": 1}},
+		{map[string]int{"abc": 1, "ABC": 2, "123": 3}, "d", map[string]int{"?l?l?l": 1}, map[string]int{"abc": 1}},
 	}
 
 	// Run test cases
 	for _, test := range tests {
-		output := MakeMatchedMaskedMap(test.input, test.replacements, test.masks, false, false)
+		output := MakeMatchedMaskedMap(test.input, test.replacements, test.masks, false, false, false)
 		if utils.CheckAreMapsEqual(output, test.output) == false {
 			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
 		}
 	}
+
+	// Run verbose test cases
+	input := map[string]int{"Summer24": 1, "Winter24": 2}
+	masks := map[string]int{"?u?l?l?l?l?l?d?d": 1, "Summer*": 1}
+	output := MakeMatchedMaskedMap(input, "uldsb", masks, true, false, false)
+	expected := map[string]int{"Summer24:?u?l?l?l?l?l?d?d": 1, "Summer24:Summer*": 1, "Winter24:?u?l?l?l?l?l?d?d": 2}
+	if utils.CheckAreMapsEqual(output, expected) == false {
+		t.Errorf("Test failed: %v inputted, %v expected, %v returned", input, expected, output)
+	}
+}
+
+// Unit Test for MatchMaskPattern()
+func TestMatchMaskPattern(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input   string
+		pattern string
+		output  bool
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{"Summer24", "?u?l?l?l?l?l?d?d", true},
+		{"Summer24", "?uummer?d?d", true},
+		{"Summer24", "?u?l?l?lSummer?d?d", false},
+		{"Summer24!", "Summer*", true},
+		{"MySummer24", "*Summer*", true},
+		{"Summer", "*Summer*", true},
+		{"Winter24", "*Summer*", false},
+		{"what?", "what??", true},
+		{"what!", "what??", false},
+		{"pass 1", "pass?s?a", true},
+		{"爱1", "?b?b?b?d", true},
+		{"deadBEEF", "?h?h?h?h?H?H?H?H", true},
+		{"", "*", true},
+		{"", "?a", false},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := MatchMaskPattern(test.input, test.pattern)
+		if output != test.output {
+			t.Errorf("Test failed: %v inputted with %v, %v expected, %v returned", test.input, test.pattern, test.output, output)
+		}
+	}
+}
+
+// Unit Test for NewRetainIndex()
+func TestNewRetainIndex(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input    map[string]int
+		keywords map[string]bool
+		lengths  []int
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{map[string]int{"love": 1, "you": 1, "pass": 1, "password": 1}, map[string]bool{"love": true, "you": true, "pass": true, "password": true}, []int{8, 4, 3}},
+		{map[string]int{"": 1, "a": 1}, map[string]bool{"a": true}, []int{1}},
+		{nil, map[string]bool{}, nil},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := NewRetainIndex(test.input)
+		if !reflect.DeepEqual(output.Keywords, test.keywords) || !reflect.DeepEqual(output.Lengths, test.lengths) {
+			t.Errorf("Test failed: %v inputted, %v %v expected, %v returned", test.input, test.keywords, test.lengths, output)
+		}
+	}
+}
+
+// Unit Test for FindRetainOccurrences()
+func TestFindRetainOccurrences(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  string
+		retain map[string]int
+		output [][2]int
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{"loveyoulove1", map[string]int{"love": 1, "you": 1}, [][2]int{{0, 4}, {4, 7}, {7, 11}}},
+		{"password1", map[string]int{"pass": 1, "password": 1, "word": 1}, [][2]int{{0, 8}, {0, 4}, {4, 8}}},
+		{"aaa", map[string]int{"aa": 1}, [][2]int{{0, 2}, {1, 3}}},
+		{"test", map[string]int{"love": 1}, nil},
+		{"cafélove", map[string]int{"é": 1, "love": 1, "": 1}, [][2]int{{3, 5}, {5, 9}}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := FindRetainOccurrences(test.input, NewRetainIndex(test.retain))
+		if !reflect.DeepEqual(output, test.output) {
+			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
+		}
+	}
+}
+
+// Unit Test for SelectLongestRetainOccurrences()
+func TestSelectLongestRetainOccurrences(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  [][2]int
+		output [][2]int
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{[][2]int{{0, 4}, {4, 7}, {7, 11}}, [][2]int{{0, 4}, {4, 7}, {7, 11}}},
+		{[][2]int{{0, 8}, {0, 4}, {4, 8}}, [][2]int{{0, 8}}},
+		{[][2]int{{0, 2}, {1, 5}, {4, 6}}, [][2]int{{1, 5}}},
+		{[][2]int{{0, 2}, {1, 3}}, [][2]int{{0, 2}}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := SelectLongestRetainOccurrences(test.input)
+		if !reflect.DeepEqual(output, test.output) {
+			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
+		}
+	}
+}
+
+// Unit Test for CombineRetainOccurrences()
+func TestCombineRetainOccurrences(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input  [][2]int
+		output [][][2]int
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{[][2]int{{0, 2}}, [][][2]int{{{0, 2}}}},
+		{[][2]int{{0, 2}, {2, 4}}, [][][2]int{{{0, 2}}, {{0, 2}, {2, 4}}, {{2, 4}}}},
+		{[][2]int{{0, 2}, {1, 3}}, [][][2]int{{{0, 2}}, {{1, 3}}}},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := CombineRetainOccurrences(test.input)
+		if !reflect.DeepEqual(output, test.output) {
+			t.Errorf("Test failed: %v inputted, %v expected, %v returned", test.input, test.output, output)
+		}
+	}
 }
 
 // Unit Test for BoundarySplitPopMap()
@@ -372,3 +652,32 @@ func TestIsMaskAFullMask(t *testing.T) {
 		}
 	}
 }
+
+// Unit Test for IsMaskInReplacementMask()
+func TestIsMaskInReplacementMask(t *testing.T) {
+	// Define a test case struct
+	type testCase struct {
+		input        string
+		replacements string
+		output       bool
+	}
+
+	type testCases []testCase
+
+	// Define test cases
+	tests := testCases{
+		{"?l?l?l?d?d?d", "uldsb", true},
+		{"?a?a?a", "uldsb", false},
+		{"?l?l?a", "uldsb", false},
+		{"?l?l?l", "d", false},
+		{"?l?l?l123", "uldsb", false},
+	}
+
+	// Run test cases
+	for _, test := range tests {
+		output := IsMaskInReplacementMask(test.input, test.replacements)
+		if output != test.output {
+			t.Errorf("Test failed: %v inputted with %v, %v expected, %v returned", test.input, test.replacements, test.output, output)
+		}
+	}
+}
@@ -65,6 +65,159 @@ type TemplateFileOperation struct {
 	TransformationMode string
 	WordRangeStart     int
 	WordRangeEnd       int
+	Proxy              string
+	Headers            []string
+	CookieFile         string
+	Insecure           bool
+	Timeout            int
+	UserAgent          string
+}
+
+// ----------------------------------------------------------------------------
+// Credential Models
+// ----------------------------------------------------------------------------
+// These models are used to store accounts parsed from credential dumps. The
+// intention is to keep usernames alongside cracked plaintext so passwords can
+// be analyzed per account.
+
+// Credential is used to store an account from a pwdump, NTDS, or secretsdump
+// file along with its plaintext if it has been cracked
+type Credential struct {
+	Domain    string
+	Username  string
+	RID       string
+	LMHash    string
+	NTHash    string
+	Plaintext string
+	Cracked   bool
+}
+
+// ----------------------------------------------------------------------------
+// Keyword Models
+// ----------------------------------------------------------------------------
+// These models are used to find dictionary keywords inside strings. The
+// intention is to index a dictionary once so every item can be searched
+// without comparing it to every keyword.
+
+// RetainIndex is used to store a set of keywords and their distinct byte
+// lengths from longest to shortest so substrings can be looked up by length
+type RetainIndex struct {
+	Keywords map[string]bool
+	Lengths  []int
+}
+
+// ----------------------------------------------------------------------------
+// Crawling Models
+// ----------------------------------------------------------------------------
+// These models are used to configure the same-site crawler and HTTP client
+// used for URL input. The intention is to keep the scope, limits, and request
+// settings in one place so they can be passed through the URL loading
+// functions.
+
+// CrawlOptions is used to store the depth, scope, and limits of a crawl
+type CrawlOptions struct {
+	Depth        int
+	MaxPages     int
+	Include      []string
+	Exclude      []string
+	Sitemap      bool
+	IgnoreRobots bool
+}
+
+// Enabled returns true if the options require crawling instead of fetching
+// only the given URLs
+func (c CrawlOptions) Enabled() bool {
+	return c.Depth > 0 || c.Sitemap
+}
+
+// HTTPOptions is used to store the settings of the HTTP client used for URL
+// input such as the proxy, extra headers, cookies, rate limits, and cache
+type HTTPOptions struct {
+	Proxy             string
+	Headers           []string
+	CookieFile        string
+	Insecure          bool
+	Timeout           int
+	UserAgent         string
+	RequestsPerSecond float64
+	Concurrency       int
+	CacheDir          string
+	Offline           bool
+}
+
+// URLStats is used to count the results of URL requests for the summary
+// printed at the end of a run
+type URLStats struct {
+	Fetched   int
+	Failed    int
+	Throttled int
+}
+
+// RobotsRules is used to store the rules of a robots.txt file that apply to
+// the crawler along with the sitemaps it lists
+type RobotsRules struct {
+	Allow    []string
+	Disallow []string
+	Sitemaps []string
+}
+
+// ----------------------------------------------------------------------------
+// Extraction Models
+// ----------------------------------------------------------------------------
+// These models are used to configure how text is extracted from URL responses
+// and documents. The intention is to keep the extraction settings together so
+// they can be passed from the command line to the document parsers.
+
+// ExtractOptions is used to store the HTML sources and JSON paths to extract
+// from URL responses and documents and the punctuation that splits phrases
+type ExtractOptions struct {
+	HTMLSources []string
+	JSONPaths   []string
+	Punctuation string
+}
+
+// ----------------------------------------------------------------------------
+// Provenance Models
+// ----------------------------------------------------------------------------
+// These models are used to report the sources that produced each item. The
+// intention is to show which inputs contributed to the final output after
+// the sources are combined.
+
+// ProvenanceRecord is used to store an item with its frequency and the
+// sources that produced it
+type ProvenanceRecord struct {
+	Item    string   `json:"item"`
+	Count   int      `json:"count"`
+	Sources []string `json:"sources"`
+}
+
+// ----------------------------------------------------------------------------
+// Structured Output Models
+// ----------------------------------------------------------------------------
+// These models are used to write the output as JSON Lines or CSV records. The
+// intention is to stream one record per item so the frequency and optional
+// metadata of each item can be saved and imported again without loss.
+
+// OutputRecord is used to store an item with its frequency and the optional
+// metadata written with it
+type OutputRecord struct {
+	Item      string   `json:"item"`
+	Count     int      `json:"count"`
+	Mask      string   `json:"mask,omitempty"`
+	Length    int      `json:"length,omitempty"`
+	Category  []string `json:"category,omitempty"`
+	Sources   []string `json:"sources,omitempty"`
+	HashTypes []string `json:"hashtypes,omitempty"`
+}
+
+// OutputOptions is used to store the options for JSON Lines and CSV output
+type OutputOptions struct {
+	Format          string
+	Fields          []string
+	ReplacementMask string
+	Encoding        string
+	Provenance      map[string][]string
+	HashTypes       map[string][]string
 }
 
 // ----------------------------------------------------------------------------
@@ -4,6 +4,7 @@ package transform
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/jakewnuk/ptt/pkg/format"
@@ -38,7 +39,92 @@ import (
 // Returns:
 //
 //	(map[string]int): A map of transformed values
-func TransformationController(input map[string]int, mode string, startingIndex int, endingIndex int, verbose bool, replacementMask string, transformationFilesMap map[string]int, bypass bool, debug int, wordRangeStart int, wordRangeEnd int) (output map[string]int) {
+func TransformationController(input map[string]int, mode string, startingIndex int, endingIndex int, verbose bool, replacementMask string, transformationFilesMap map[string]int, bypass bool, debug int, wordRangeStart int, wordRangeEnd int) map[string]int {
+	printTransformationNotice(mode)
+	return transformMap(input, mode, startingIndex, endingIndex, verbose, replacementMask, transformationFilesMap, bypass, debug, wordRangeStart, wordRangeEnd)
+}
+
+// TransformationSourceController applies a transformation like
+// TransformationController while tracking the sources of each value. Values
+// produced by the same sources are transformed together so the input is
+// transformed once and each output value is credited to the sources of the
+// values it came from.
+//
+// Args:
+//
+//	input (map[string]int): A map of input values
+//	sources (map[string][]string): The sources that produced each input value
+//	mode (string): The mode to run the CLI in
+//	startingIndex (int): The starting index for the transformation if applicable
+//	endIndex (int): The ending index for the transformation if applicable
+//	verbose (bool): If true, the verbose information is printed when available
+//	replacementMask (string): The mask characters to use for masking operations
+//	transformationFilesMap (map[string]int): A map of transformation files to
+//	use for modes like retain-mask
+//	bypass (bool): If true, the map is not used for output or filtering
+//	debug (int): Different debug levels to use for debugging [0-2]
+//	wordRangeStart (int): The starting range for word operations
+//	wordRangeEnd (int): The ending range for word operations
+//
+// Returns:
+//
+//	(map[string]int): A map of transformed values
+//	(map[string][]string): The sources that produced each transformed value
+func TransformationSourceController(input map[string]int, sources map[string][]string, mode string, startingIndex int, endingIndex int, verbose bool, replacementMask string, transformationFilesMap map[string]int, bypass bool, debug int, wordRangeStart int, wordRangeEnd int) (map[string]int, map[string][]string) {
+	printTransformationNotice(mode)
+
+	// Group the input by the sources that produced each value
+	groups := make(map[string]map[string]int)
+	groupSources := make(map[string][]string)
+	for key, value := range input {
+		group := strings.Join(slices.Sorted(slices.Values(sources[key])), "\n")
+		if groups[group] == nil {
+			groups[group] = make(map[string]int)
+			groupSources[group] = sources[key]
+		}
+		groups[group][key] = value
+	}
+
+	output := make(map[string]int)
+	outputSources := make(map[string][]string)
+	for group, groupInput := range groups {
+		groupOutput := transformMap(groupInput, mode, startingIndex, endingIndex, verbose, replacementMask, transformationFilesMap, bypass, debug, wordRangeStart, wordRangeEnd)
+		for key, value := range groupOutput {
+			output[key] += value
+		}
+		for _, source := range groupSources[group] {
+			utils.AddProvenance(outputSources, groupOutput, source)
+		}
+	}
+
+	return output, outputSources
+}
+
+// transformationNotices are the notices printed once for transformation
+// modes that expect a particular input
+var transformationNotices = map[string]string{
+	"swap":          "This transformation mode requires a ':' separated list of keys to swap.",
+	"swap-single":   "This transformation mode requires a ':' separated list of keys to swap.",
+	"mask-swap":     "This transformation mode requires a retain mask file to use for swapping.",
+	"passphrase":    "This transformation mode expects space separated content.",
+	"regram":        "This transformation mode expects space separated content.",
+	"rule-apply":    "This transformation mode expects a rule file to apply.",
+	"apply":         "This transformation mode expects a rule file to apply.",
+	"rule-simplify": "This transformation mode expects rule input to simplify.",
+	"simplify":      "This transformation mode expects rule input to simplify.",
+}
+
+// printTransformationNotice prints the notice of a transformation mode if it
+// has one
+func printTransformationNotice(mode string) {
+	if notice, ok := transformationNotices[mode]; ok {
+		fmt.Fprintf(os.Stderr, "[*] %s\n", notice)
+	}
+}
+
+// transformMap applies a transformation to a map of input values for
+// TransformationController and TransformationSourceController
+func transformMap(input map[string]int, mode string, startingIndex int, endingIndex int, verbose bool, replacementMask string, transformationFilesMap map[string]int, bypass bool, debug int, wordRangeStart int, wordRangeEnd int) (output map[string]int) {
 
 	functionDebug := false
 	if debug > 1 {
@@ -81,6 +167,8 @@ func TransformationController(input map[string]int, mode string, startingIndex i
 		output = format.DecodeInputMap(input, bypass, functionDebug)
 	case "mask":
 		output = mask.MakeMaskedMap(input, replacementMask, verbose, bypass, functionDebug)
+	case "mask-hex", "hex-mask":
+		output = mask.MakeHexCharsetMaskedMap(input, replacementMask, bypass, functionDebug)
 	case "dehex":
 		output = format.DehexMap(input, bypass, functionDebug)
 	case "hex":
@@ -93,15 +181,20 @@ func TransformationController(input map[string]int, mode string, startingIndex i
 			fmt.Fprintf(os.Stderr, "[!] Retain masks require use of one or more -tf flags to specify one or more files.\n")
 			os.Exit(1)
 		}
-		output = mask.MakeRetainMaskedMap(input, replacementMask, transformationFilesMap, bypass, functionDebug, verbose)
+		output = mask.MakeRetainMaskedMap(input, replacementMask, transformationFilesMap, bypass, functionDebug, verbose, false)
+	case "mask-retain-combinations", "retain-combinations":
+		if len(transformationFilesMap) == 0 {
+			fmt.Fprintf(os.Stderr, "[!] Retain masks require use of one or more -tf flags to specify one or more files.\n")
+			os.Exit(1)
+		}
+		output = mask.MakeRetainMaskedMap(input, replacementMask, transformationFilesMap, bypass, functionDebug, verbose, true)
 	case "mask-match", "match":
 		if len(transformationFilesMap) == 0 {
 			fmt.Fprintf(os.Stderr, "[!] Match masks require use of one or more -tf flags to specify one or more files.\n")
 			os.Exit(1)
 		}
-		output = mask.MakeMatchedMaskedMap(input, replacementMask, transformationFilesMap, bypass, functionDebug)
+		output = mask.MakeMatchedMaskedMap(input, replacementMask, transformationFilesMap, verbose, bypass, functionDebug)
 	case "swap", "swap-single":
-		fmt.Fprintf(os.Stderr, "[*] This transformation mode requires a ':' separated list of keys to swap.\n")
 		if len(transformationFilesMap) == 0 {
 			fmt.Fprintf(os.Stderr, "[!] Swap operations require use of one or more -tf flags to specify one or more files.\n")
 			os.Exit(1)
@@ -110,14 +203,12 @@ func TransformationController(input map[string]int, mode string, startingIndex i
 	case "mask-pop", "pop":
 		output = mask.BoundarySplitPopMap(input, replacementMask, bypass, functionDebug)
 	case "mask-swap":
-		fmt.Fprintf(os.Stderr, "[*] This transformation mode requires a retain mask file to use for swapping.\n")
 		if len(transformationFilesMap) == 0 {
 			fmt.Fprintf(os.Stderr, "[!] Mask-swap operations require use of one or more -tf flags to specify one or more files.\n")
 			os.Exit(1)
 		}
 		output = mask.ShuffleMap(input, replacementMask, transformationFilesMap, bypass, functionDebug)
 	case "passphrase":
-		fmt.Fprintf(os.Stderr, "[*] This transformation mode expects space separated content.\n")
 		if wordRangeStart == 0 {
 			fmt.Fprintf(os.Stderr, "[!] Passphrase operations require use of the -w flag to specify the number of words to use.\n")
 			os.Exit(1)
@@ -132,22 +223,21 @@ func TransformationController(input map[string]int, mode string, startingIndex i
 		}
 		output = ReplaceAllKeysInMap(input, transformationFilesMap, bypass, functionDebug)
 	case "regram":
-		fmt.Fprintf(os.Stderr, "[*] This transformation mode expects space separated content.\n")
 		if wordRangeStart == 0 {
 			fmt.Fprintf(os.Stderr, "[!] Regram operations require use of the -w flag to specify the number of words to use.\n")
 			os.Exit(1)
 		}
 		output = GenerateNGramMap(input, wordRangeStart, wordRangeEnd, bypass, functionDebug)
 	case "rule-apply", "apply":
-		fmt.Fprintf(os.Stderr, "[*] This transformation mode expects a rule file to apply.\n")
 		if len(transformationFilesMap) == 0 {
 			fmt.Fprintf(os.Stderr, "[!] Apply operations require use of one or more -tf flags to specify one or more files.\n")
 			os.Exit(1)
 		}
 		output = rule.ApplyRulesHCRE(input, transformationFilesMap, bypass, functionDebug)
 	case "rule-simplify", "simplify":
-		fmt.Fprintf(os.Stderr, "[*] This transformation mode expects rule input to simplify.\n")
 		output = rule.SimplifyRules(input, bypass, functionDebug)
+	case "markov":
+		output = format.CreateMarkovMap(input, bypass, functionDebug)
 	default:
 		output = input
 	}
@@ -1,6 +1,8 @@
 package transform
 
 import (
+	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/jakewnuk/ptt/pkg/utils"
@@ -9,6 +11,9 @@ import (
 // ----------------------------------------------------------------------------
 // Functions with Unit Tests
 // ----------------------------------------------------------------------------
+// ** TransformationController **
+// - TransformationSourceController()
+//
 // ** Generation Functions **
 // - ReplaceKeysInMap()
 // - ReplaceAllKeysInMap()
@@ -74,3 +79,36 @@ func TestReplaceAllKeysInMap(t *testing.T) {
 		}
 	}
 }
+
+// Unit Test for TransformationSourceController
+func TestTransformationSourceController(t *testing.T) {
+
+	// Define a test case struct
+	type testCase struct {
+		input   map[string]int
+		sources map[string][]string
+		mode    string
+		output  map[string]int
+		origins map[string][]string
+	}
+
+	type testCases []testCase
+
+	// Define the test cases
+	tests := testCases{
+		{map[string]int{"love1": 2, "hate2": 1, "pass3": 4}, map[string][]string{"love1": {"a.txt"}, "hate2": {"b.txt"}, "pass3": {"b.txt", "a.txt"}}, "mask", map[string]int{"?l?l?l?l?d": 7}, map[string][]string{"?l?l?l?l?d": {"a.txt", "b.txt"}}},
+		{map[string]int{"love1": 2, "爱2": 1}, map[string][]string{"love1": {"a.txt"}, "爱2": {"b.txt"}}, "mask", map[string]int{"?l?l?l?l?d": 2, "爱?d": 1}, map[string][]string{"?l?l?l?l?d": {"a.txt"}, "爱?d": {"b.txt"}}},
+		{map[string]int{"love": 1, "hate": 3}, map[string][]string{"love": {"a.txt"}, "hate": {"a.txt", "b.txt"}}, "", map[string]int{"love": 1, "hate": 3}, map[string][]string{"love": {"a.txt"}, "hate": {"a.txt", "b.txt"}}},
+	}
+
+	// Run the test cases
+	for _, test := range tests {
+		result, origins := TransformationSourceController(test.input, test.sources, test.mode, 0, 0, false, "uld", nil, false, 0, 0, 0)
+		for _, sources := range origins {
+			slices.Sort(sources)
+		}
+		if utils.CheckAreMapsEqual(result, test.output) == false || !reflect.DeepEqual(origins, test.origins) {
+			t.Errorf("Test case failed. Expected %v %v, got %v %v", test.output, test.origins, result, origins)
+		}
+	}
+}
@@ -2,29 +2,52 @@
 package utils
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
+	"github.com/jakewnuk/ptt/pkg/document"
 	"github.com/jakewnuk/ptt/pkg/models"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 	"golang.org/x/net/html"
 	"golang.org/x/text/cases"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	textunicode "golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/language"
+	"golang.org/x/text/transform"
 )
 
 // ----------------------------------------------------------------------------
@@ -63,66 +86,80 @@ func GetMemoryUsage() float64 {
 	return float64(m.Alloc) / 1024 / 1024
 }
 
+// DefaultMaxLineLength is the default maximum length of a line in bytes when
+// reading input
+const DefaultMaxLineLength = 1024 * 1024
+
+// skippedLines counts the lines longer than the maximum length that were
+// skipped by every line scanner
+var skippedLines atomic.Int64
+
 // ReadFilesToMap reads the contents of the multiple files and returns a map of words
 //
 // Args:
 //
 //	fs (FileSystem): The filesystem to read the files from (used for testing)
 //	filenames ([]string): The names of the files to read
+//	maxLineLength (int): The maximum length of a line in bytes
+//	inputEncoding (string): The encoding of the files, "auto" to detect, or
+//	empty to read as UTF-8
+//	header (bool): If true, CSV and TSV files have a header row that is
+//	skipped when a column is selected by number
+//	include ([]string): Glob patterns of directory and archive members to read
+//	exclude ([]string): Glob patterns of directory and archive members to skip
 //
 // Returns:
 //
 //	(map[string]int): A map of words from the files
-func ReadFilesToMap(fs models.FileSystem, filenames []string) map[string]int {
+func ReadFilesToMap(fs models.FileSystem, filenames []string, maxLineLength int, inputEncoding string, header bool, include []string, exclude []string) map[string]int {
 	wordMap := make(map[string]int)
-	// 1 GB read buffer
-	chunkSize := int64(1 * 1024 * 1024 * 1024)
 
 	i := 0
 	for i < len(filenames) {
 		filename := filenames[i]
-		if IsFileSystemDirectory(filename) {
-			files, err := GetFilesInDirectory(filename)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error reading the directory %v: %v.\n", filename, err)
-				os.Exit(1)
-			}
-			filenames = append(filenames, files...)
-		} else {
-			file, err := fs.Open(filename)
+		path, selector := SplitColumnSelector(fs, filename)
+		if IsFileSystemDirectory(path) {
+			files, err := GetFilesInDirectory(path)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error opening file %s.\n", filename)
+				fmt.Fprintf(os.Stderr, "[!] Error reading the directory %v: %v.\n", path, err)
 				os.Exit(1)
 			}
-			defer file.Close()
 
-			buffer := make([]byte, chunkSize)
-			for {
-				bytesRead, err := file.Read(buffer)
-				if err != nil && err != io.EOF {
-					fmt.Fprintf(os.Stderr, "[!] Error reading file %s.\n", filename)
-					os.Exit(1)
+			// Apply the glob patterns and column selector to every file in the directory
+			for _, file := range files {
+				if !MatchArchiveGlobs(file, include, exclude) {
+					continue
 				}
-				if bytesRead == 0 {
-					break
+				if selector != "" {
+					file += "#" + selector
 				}
-
-				data := buffer[:bytesRead]
-
-				err = json.Unmarshal(data, &wordMap)
-				if err == nil {
-					fmt.Fprintf(os.Stderr, "[*] Detected ptt JSON output. Importing...\n")
-					continue
+				filenames = append(filenames, file)
+			}
+		} else if IsArchiveFile(path) {
+			err := WalkArchive(fs, path, include, exclude, func(name string, reader io.Reader) error {
+				memberMap, err := ReadInputToMap(reader, name, selector, maxLineLength, inputEncoding, header)
+				if err != nil {
+					return fmt.Errorf("%s: %s", name, err)
 				}
 
-				fileWords := strings.Split(string(data), "\n")
-				for _, word := range fileWords {
-					wordMap[word]++
+				for word, count := range memberMap {
+					wordMap[word] += count
 				}
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error reading archive %s: %s.\n", path, err)
+				os.Exit(1)
+			}
+		} else {
+			fileMap, err := ReadFileToMap(fs, filename, maxLineLength, inputEncoding, header)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error reading file %s: %s.\n", filename, err)
+				os.Exit(1)
+			}
 
-				if err == io.EOF {
-					break
-				}
+			for word, count := range fileMap {
+				wordMap[word] += count
 			}
 		}
 		i++
@@ -134,566 +171,3292 @@ func ReadFilesToMap(fs models.FileSystem, filenames []string) map[string]int {
 	return wordMap
 }
 
-// LoadStdinToMap reads the contents of stdin and returns a map[string]int
-// where the key is the line and the value is the frequency of the line
-// in the input
+// ReadFileToMap reads the contents of a single file and returns a map of
+// words. Filenames in the file#column format extract a single column or field
+// from CSV, TSV, or JSON Lines files.
 //
 // Args:
 //
-//	scanner (models.Scanner): The scanner to read from stdin
+//	fs (FileSystem): The filesystem to read the file from (used for testing)
+//	filename (string): The name of the file to read
+//	maxLineLength (int): The maximum length of a line in bytes
+//	inputEncoding (string): The encoding of the file, "auto" to detect, or
+//	empty to read as UTF-8
+//	header (bool): If true, CSV and TSV files have a header row that is
+//	skipped when a column is selected by number
 //
 // Returns:
 //
-//	map[string]int: A map of lines from stdin
+//	map[string]int: A map of words from the file
 //	error: An error if one occurred
-func LoadStdinToMap(scanner models.Scanner) (map[string]int, error) {
-	m := make(map[string]int)
-	pttInput := false
-	line0 := false
-	reDetect := regexp.MustCompile(`^\d+\s(\w+|\W+)$`)
-	reParse := regexp.MustCompile(`^\d+`)
-
-	for scanner.Scan() {
-		if scanner.Text() == "" {
-			continue
-		}
+func ReadFileToMap(fs models.FileSystem, filename string, maxLineLength int, inputEncoding string, header bool) (map[string]int, error) {
+	filename, selector := SplitColumnSelector(fs, filename)
+	file, err := fs.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
-		// Detect ptt -v output
-		if matched := reDetect.MatchString(scanner.Text()); matched && pttInput == false && line0 == false {
-			fmt.Fprintf(os.Stderr, "[*] Detected ptt -v output. Importing...\n")
-			pttInput = true
-		}
+	return ReadInputToMap(file, filename, selector, maxLineLength, inputEncoding, header)
+}
 
-		if pttInput {
-			line := scanner.Text()
-			match := reParse.FindString(line)
-			value, err := strconv.Atoi(match)
-			if err != nil {
-				return nil, err
-			}
-			newLine := strings.TrimSpace(strings.Replace(line, match, "", 1))
-			m[newLine] += value
+// RecordSniffLength is the number of bytes of ptt JSON, JSON Lines, or CSV
+// output that are kept while importing so input that turns out not to be ptt
+// output can be read again as lines
+const RecordSniffLength = 64 * 1024
 
-		} else {
-			line := scanner.Text()
-			m[line]++
-		}
-		line0 = true
-	}
+// sniffBuffer keeps the bytes written to it until they exceed the limit and
+// then discards them so importing large input does not keep a second copy
+type sniffBuffer struct {
+	data     bytes.Buffer
+	limit    int
+	overflow bool
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// Write implements the io.Writer interface
+func (b *sniffBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	} else if b.data.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.data = bytes.Buffer{}
+		return len(p), nil
 	}
-
-	return m, nil
+	return b.data.Write(p)
 }
 
-// ReadURLsToMap reads the contents of the multiple URLs and returns a map of words
-// from the URLs. Supports files or directories containing URLs.
+// ReadInputToMap reads the contents of a file or archive member and returns a
+// map of words. Compressed input is decompressed, transcoded to UTF-8, and ptt
+// JSON, JSON Lines, and CSV output is detected by sniffing the first bytes of
+// the input. Input that stops being valid ptt output within the first
+// RecordSniffLength bytes is read as lines and later errors are returned.
 //
 // Args:
 //
-//	urls ([]string): The URLs to read
-//	parsingMode (int): Change parsing mode for URL input. [0 = Strict, 1 = Permissive, 2 = Maximum] [0-2].
-//	debugMode (int): A flag to print debug information
+//	input (io.Reader): The reader of the file contents
+//	name (string): The name of the file used to detect the column format
+//	selector (string): The column number or field name to extract if any
+//	maxLineLength (int): The maximum length of a line in bytes
+//	inputEncoding (string): The encoding of the input, "auto" to detect, or
+//	empty to read as UTF-8
+//	header (bool): If true, CSV and TSV input has a header row that is
+//	skipped when a column is selected by number
 //
 // Returns:
 //
-//	map[string]int: A map of words from the URLs
+//	map[string]int: A map of words from the input
 //	error: An error if one occurred
-func ReadURLsToMap(urls []string, parsingMode int, debugMode int) (map[string]int, error) {
-	wordMap := make(map[string]int)
-	var wg sync.WaitGroup
+func ReadInputToMap(input io.Reader, name string, selector string, maxLineLength int, inputEncoding string, header bool) (map[string]int, error) {
+	reader, err := GetDecompressedReader(input)
+	if err != nil {
+		return nil, err
+	}
 
-	ch := make(chan string)
+	reader, err = GetDecodedReader(reader, inputEncoding)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(reader)
 
-	go func() {
-		for word := range ch {
-			wordMap[word]++
+	if selector != "" {
+		return ReadColumnToMap(buffered, name, selector, maxLineLength, header)
+	}
+
+	if IsCSVRecordInput(buffered) {
+		consumed := &sniffBuffer{limit: RecordSniffLength}
+		wordMap, err := ReadCSVRecordsToMap(io.TeeReader(buffered, consumed))
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "[*] Detected ptt CSV output. Importing...\n")
+			return wordMap, nil
+		} else if consumed.overflow {
+			return nil, fmt.Errorf("invalid ptt CSV output: %s", err)
 		}
-	}()
 
-	sleepOnStart := true
-	for _, iURL := range urls {
-		if IsValidURL(iURL) {
-			wg.Add(1)
-			go ProcessURL(iURL, ch, &wg, parsingMode, debugMode, sleepOnStart)
+		// Not ptt CSV so read the consumed bytes again as lines
+		buffered = bufio.NewReader(io.MultiReader(&consumed.data, buffered))
+	}
 
-		} else if IsFileSystemDirectory(iURL) {
-			files, err := GetFilesInDirectory(iURL)
-			if err != nil {
-				return nil, err
-			}
-			for _, file := range files {
-				wg.Add(1)
-				go ProcessURLFile(file, ch, &wg, parsingMode, debugMode)
-			}
-		} else if IsValidFile(iURL) {
-			wg.Add(1)
-			go ProcessURLFile(iURL, ch, &wg, parsingMode, debugMode)
-		} else {
-			fmt.Fprintf(os.Stderr, "[!] Rejected URL or file: %s.\n", iURL)
-			return nil, fmt.Errorf("invalid input: %s", iURL)
+	if IsJSONInput(buffered) {
+		consumed := &sniffBuffer{limit: RecordSniffLength}
+		wordMap, err := ReadJSONToMap(io.TeeReader(buffered, consumed))
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "[*] Detected ptt JSON output. Importing...\n")
+			return wordMap, nil
+		} else if consumed.overflow {
+			return nil, fmt.Errorf("invalid ptt JSON output: %s", err)
 		}
+
+		// Not ptt JSON so read the consumed bytes again as lines
+		buffered = bufio.NewReader(io.MultiReader(&consumed.data, buffered))
 	}
 
-	wg.Wait()
-	close(ch)
+	return ReadLinesToMap(buffered, maxLineLength)
+}
 
-	delete(wordMap, "")
+// SplitColumnSelector splits a filename in the file#column format into the
+// path and the column selector. The selector is empty if none is present.
+// Existing files and directories with a # in the name are not split.
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to check for existing files (used for
+//	testing)
+//	filename (string): The filename to split
+//
+// Returns:
+//
+//	string: The path of the file
+//	string: The column number or field name
+func SplitColumnSelector(fs models.FileSystem, filename string) (string, string) {
+	index := strings.LastIndex(filename, "#")
+	if index == -1 || index == len(filename)-1 || strings.ContainsAny(filename[index+1:], "/\\") {
+		return filename, ""
+	} else if file, err := fs.Open(filename); err == nil {
+		file.Close()
+		return filename, ""
+	}
 
-	return wordMap, nil
+	return filename[:index], filename[index+1:]
 }
 
-// CombineMaps combines any number of maps into a single map combining values for common keys
-// and returning a new map
+// SplitSourceWeight splits a file, document, or URL argument in the
+// source:weight format into the source and its weight. The weight is 1 if none
+// is present. Existing files with a colon in the name and URL ports are not
+// treated as weights.
 //
 // Args:
-// maps ([]map[string]int): The maps to combine
+//
+//	source (string): The argument to split
 //
 // Returns:
-// map[string]int: A new map combining the values of the input maps
-func CombineMaps(maps ...map[string]int) map[string]int {
-	var result sync.Map
+//
+//	string: The source without the weight
+//	float64: The weight of the source
+//	error: An error if the weight is not a positive number
+func SplitSourceWeight(source string) (string, float64, error) {
+	index := strings.LastIndex(source, ":")
+	if index <= 0 || index == len(source)-1 {
+		return source, 1, nil
+	}
 
-	for _, m := range maps {
-		for k, v := range m {
-			if val, ok := result.Load(k); ok {
-				result.Store(k, val.(int)+v)
-			} else {
-				result.Store(k, v)
-			}
+	suffix := source[index+1:]
+	weight, err := strconv.ParseFloat(suffix, 64)
+	if err != nil || IsValidFile(source) {
+		return source, 1, nil
+	}
+	if parsed, err := url.Parse(source); err == nil && parsed.Host != "" {
+		if _, port, err := net.SplitHostPort(parsed.Host); err == nil && port == suffix {
+			return source, 1, nil
 		}
 	}
 
-	finalResult := make(map[string]int)
-	result.Range(func(k, v interface{}) bool {
-		finalResult[k.(string)] = v.(int)
-		return true
-	})
+	if weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return "", 0, fmt.Errorf("invalid weight %s for %s", suffix, source[:index])
+	}
 
-	return finalResult
+	return source[:index], weight, nil
 }
 
-// ProcessURL reads the contents of a URL and sends each sentence to the channel
+// TrimCompressionExtension removes a gzip, bzip2, xz, or zstd extension from a
+// filename so the format of the compressed contents can be detected
 //
 // Args:
 //
-//	url (string): The URL to read
-//	ch (chan<- string): The channel to send the sentences to
-//	wg (*sync.WaitGroup): The WaitGroup to signal when done
-//	parsingMode (int): Change parsing mode for URL input. [0 = Strict,
-//	1 = Permissive, 2 = Maximum] [0-2].
-//	debugMode (int): A flag to print debug information
-//	sleepOnStart (bool): A flag to sleep before starting the request
+//	filename (string): The filename to trim
 //
 // Returns:
 //
-//	None
-func ProcessURL(url string, ch chan<- string, wg *sync.WaitGroup, parsingMode int, debugMode int, sleepOnStart bool) {
-	defer wg.Done()
-	var resp *http.Response
-	throttleInterval := 90
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
-	const maxRetries = 3
-	userAgents := []string{
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Safari/605.1.15",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.96 Safari/537.36",
-		"Mozilla/5.0 (iPhone; CPU iPhone OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A372 Safari/604.1",
-		"Mozilla/5.0 (iPad; CPU OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A5341f Safari/604.1",
-		"Mozilla/5.0 (Linux; Android 11; Pixel 4) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.181 Mobile Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
-		"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Safari/605.1.15",
-	}
-
-	if sleepOnStart {
-		time.Sleep(time.Second * time.Duration(r.Intn(throttleInterval)))
-	}
-
-	for attempts := 0; attempts <= maxRetries; attempts++ {
-
-		// Set a random user agent
-		randomUserAgent := userAgents[r.Intn(len(userAgents))]
-
-		// Fetch the URL
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", url, nil)
-		req.Header.Set("User-Agent", randomUserAgent)
-		resp, err = client.Do(req)
+//	string: The filename without the compression extension
+func TrimCompressionExtension(filename string) string {
+	for _, extension := range []string{".gz", ".bz2", ".xz", ".zst"} {
+		if strings.HasSuffix(strings.ToLower(filename), extension) {
+			return filename[:len(filename)-len(extension)]
+		}
+	}
+	return filename
+}
+
+// DetectColumnFormat returns the format of a file for column extraction as
+// "csv", "tsv", or "jsonl". The file extension is used first and otherwise the
+// first line is sniffed without consuming it.
+//
+// Args:
+//
+//	filename (string): The name of the file
+//	reader (*bufio.Reader): The reader of the file contents
+//
+// Returns:
+//
+//	string: The detected format
+func DetectColumnFormat(filename string, reader *bufio.Reader) string {
+	name := TrimCompressionExtension(strings.ToLower(filename))
+
+	switch filepath.Ext(name) {
+	case ".csv":
+		return "csv"
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	}
+
+	if IsJSONInput(reader) {
+		return "jsonl"
+	}
+
+	peek, _ := reader.Peek(4096)
+	if line, _, _ := bytes.Cut(peek, []byte("\n")); bytes.Contains(line, []byte("\t")) {
+		return "tsv"
+	}
+
+	return "csv"
+}
+
+// ReadColumnToMap reads a single column or field from CSV, TSV, or JSON Lines
+// input and returns a map of values and their frequency
+//
+// Args:
+//
+//	reader (*bufio.Reader): The reader to read from
+//	filename (string): The name of the file used to detect the format
+//	selector (string): The column number starting at 1 or the field name
+//	maxLineLength (int): The maximum length of a line in bytes
+//	header (bool): If true, CSV and TSV input has a header row that is
+//	skipped when a column is selected by number
+//
+// Returns:
+//
+//	map[string]int: A map of values from the column
+//	error: An error if one occurred
+func ReadColumnToMap(reader *bufio.Reader, filename string, selector string, maxLineLength int, header bool) (map[string]int, error) {
+	switch DetectColumnFormat(filename, reader) {
+	case "jsonl":
+		return ReadJSONLinesFieldToMap(reader, selector, maxLineLength)
+	case "tsv":
+		return ReadDelimitedColumnToMap(reader, '\t', selector, header)
+	default:
+		return ReadDelimitedColumnToMap(reader, ',', selector, header)
+	}
+}
+
+// ReadDelimitedColumnToMap reads a single column from CSV or TSV input with
+// quoted fields and returns a map of values and their frequency. Numeric
+// selectors start at 1 and other selectors match a header name. The first row
+// is only read as data for numeric selectors when there is no header.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to read from
+//	delimiter (rune): The field delimiter
+//	selector (string): The column number starting at 1 or the header name
+//	header (bool): If true, the first row is a header that is skipped when
+//	a column is selected by number
+//
+// Returns:
+//
+//	map[string]int: A map of values from the column
+//	error: An error if one occurred
+func ReadDelimitedColumnToMap(reader io.Reader, delimiter rune, selector string, header bool) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = delimiter
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	csvReader.ReuseRecord = true
+
+	column, err := strconv.Atoi(selector)
+	column--
+	if err != nil {
+		header, err := csvReader.Read()
 		if err != nil {
-			if debugMode >= 2 {
-				fmt.Fprintf(os.Stderr, "[!] Error fetching URL %s.\n", url)
-			}
-			return
+			return nil, fmt.Errorf("column %s not found: %s", selector, err)
 		}
 
-		if resp == nil {
-			if debugMode >= 2 {
-				fmt.Fprintf(os.Stderr, "[!] Error no response from URL %s.\n", url)
+		column = -1
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), selector) {
+				column = i
+				break
 			}
-			return
 		}
+		if column == -1 {
+			return nil, fmt.Errorf("column %s not found in header", selector)
+		}
+	} else if column < 0 {
+		return nil, fmt.Errorf("column %s is out of range", selector)
+	} else if header {
+		if _, err := csvReader.Read(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
 
-		defer resp.Body.Close()
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
 
-		// Check the response code for throttling
-		if resp.StatusCode == http.StatusTooManyRequests {
-			throttleInterval += 30
-			time.Sleep(time.Second * time.Duration(throttleInterval*(r.Intn(3)+1)))
-			fmt.Fprintf(os.Stderr, "[!] Requested %s. Attempt [%d/%d]. Response Code: %s. Waiting %d seconds before retrying. \n", url, attempts, maxRetries, resp.Status, throttleInterval)
-		} else {
-			fmt.Fprintf(os.Stderr, "[+] Requested %s. Attempt [%d/%d]. Response Code: %s. Content-Type: %s. \n", url, attempts, maxRetries, resp.Status, resp.Header.Get("Content-Type"))
+		if column < len(record) && record[column] != "" {
+			wordMap[record[column]]++
 		}
+	}
 
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
-			// end the loop if the code is 300, 301, 302, 303, 307, 308, 400, 401, 403, 405, 500, 503
-			if resp.StatusCode == http.StatusMovedPermanently || resp.StatusCode == http.StatusFound || resp.StatusCode == http.StatusSeeOther || resp.StatusCode == http.StatusTemporaryRedirect || resp.StatusCode == http.StatusPermanentRedirect || resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusInternalServerError || resp.StatusCode == http.StatusServiceUnavailable {
+	return wordMap, nil
+}
 
-				if debugMode >= 2 {
-					fmt.Fprintf(os.Stderr, "[!] Error fetching URL service returned %s. Removing target. %s\n", resp.Status, url)
-				}
-				return
-			}
+// ReadJSONLinesFieldToMap reads a single field from JSON Lines input and
+// returns a map of values and their frequency. Nested fields are selected
+// with dots such as user.password.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to read from
+//	field (string): The name of the field
+//	maxLineLength (int): The maximum length of a line in bytes
+//
+// Returns:
+//
+//	map[string]int: A map of values from the field
+//	error: An error if one occurred
+func ReadJSONLinesFieldToMap(reader io.Reader, field string, maxLineLength int) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
 
-			if debugMode >= 2 {
-				fmt.Fprintf(os.Stderr, "[!] Error unexpected response code %s. Retrying... %s\n", resp.Status, url)
-			}
+	scanner := NewLineScanner(reader, maxLineLength)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
 			continue
 		}
 
-		break
-	}
-
-	if resp == nil {
-		if debugMode >= 1 {
-			fmt.Fprintf(os.Stderr, "[!] Error no response from URL %s.\n", url)
-		}
-		return
-	}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		if debugMode >= 1 {
-			fmt.Fprintf(os.Stderr, "[!] Error reading response body from URL %s.\n", url)
+		var object interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return nil, err
 		}
-		return
-	}
-	text := string(body)
-	text = html.UnescapeString(text)
-	var lines []string
 
-	// Check the Content-Type of the response
-	contentType := resp.Header.Get("Content-Type")
-	if strings.Contains(contentType, "text/html") {
-		// Parse the HTML
-		doc, err := html.Parse(strings.NewReader(text))
-		if err != nil {
-			if debugMode >= 1 {
-				fmt.Fprintf(os.Stderr, "[!] Error parsing HTML from URL %s.\n", url)
+		for _, key := range strings.Split(field, ".") {
+			if fields, ok := object.(map[string]interface{}); ok {
+				object = fields[key]
+			} else {
+				object = nil
 			}
-			return
 		}
 
-		// Traverse the HTML tree and extract the text
-		var f func(*html.Node)
-		f = func(n *html.Node) {
-			if n.Type == html.TextNode {
-				lines = append(lines, n.Data)
-			}
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				f(c)
+		switch value := object.(type) {
+		case string:
+			if value != "" {
+				wordMap[value]++
 			}
-		}
-		f(doc)
-	} else {
-		sentences := strings.Split(text, "\n")
-		for _, line := range sentences {
-			lines = append(lines, line)
+		case json.Number:
+			wordMap[value.String()]++
+		case bool:
+			wordMap[strconv.FormatBool(value)]++
 		}
 	}
 
-	if debugMode == 1 {
-		fmt.Fprintf(os.Stderr, "[?] URL: %s\n", url)
-		fmt.Fprintf(os.Stderr, "[?] Content-Type: %s\n", contentType)
-		fmt.Fprintf(os.Stderr, "[?] Parsing Mode: %d\n", parsingMode)
-	} else if debugMode == 2 {
-		fmt.Fprintf(os.Stderr, "[?] URL: %s\n", url)
-		fmt.Fprintf(os.Stderr, "[?] Content-Type: %s\n", contentType)
-		fmt.Fprintf(os.Stderr, "[?] Parsing Mode: %d\n", parsingMode)
-		fmt.Fprintf(os.Stderr, "[?] Line Count: %d\n", len(lines))
-		fmt.Fprintf(os.Stderr, "[?] Sample Lines:\n")
-		for i := 0; i < 5; i++ {
-			fmt.Fprintf(os.Stderr, "%s\n", lines[i])
-		}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return wordMap, nil
+}
+
+// IsJSONInput sniffs the first bytes of a reader without consuming them and
+// returns true if the input looks like a JSON object or JSON lines
+//
+// Args:
+//
+//	reader (*bufio.Reader): The reader to sniff
+//
+// Returns:
+//
+//	bool: True if the input starts with a JSON object
+func IsJSONInput(reader *bufio.Reader) bool {
+	peek, _ := reader.Peek(512)
+	peek = bytes.TrimPrefix(peek, []byte("\xef\xbb\xbf"))
+	peek = bytes.TrimLeft(peek, " \t\r\n")
+	return len(peek) > 0 && peek[0] == '{'
+}
+
+// ReadJSONToMap decodes ptt JSON output from a reader and returns a map of
+// words. Multiple objects such as JSON lines are combined and JSON Lines
+// records with item and count fields are imported with their count. Record
+// items in the $HEX[...] format are decoded.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to decode
+//
+// Returns:
+//
+//	map[string]int: A map of words from the JSON input
+//	error: An error if the input is not ptt JSON output
+func ReadJSONToMap(reader io.Reader) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	bufferedReader := bufio.NewReader(reader)
+
+	// Skip a UTF-8 byte order mark if present
+	if bom, err := bufferedReader.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		bufferedReader.Discard(3)
+	}
+
+	decoder := json.NewDecoder(bufferedReader)
+	for {
+		var object map[string]json.RawMessage
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Objects with a string item and a count are ptt JSON Lines records
+		var item string
+		if _, ok := object["count"]; ok && json.Unmarshal(object["item"], &item) == nil {
+			var count int
+			if err := json.Unmarshal(object["count"], &count); err != nil {
+				return nil, err
+			}
+			wordMap[DecodeHexString(item)] += count
+			continue
+		}
+
+		for word, value := range object {
+			var count int
+			if err := json.Unmarshal(value, &count); err != nil {
+				return nil, err
+			}
+			wordMap[word] += count
+		}
+	}
+
+	return wordMap, nil
+}
+
+// IsCSVRecordInput checks if the first line of a reader is the item and count
+// header of ptt CSV output without consuming the input
+//
+// Args:
+//
+//	reader (*bufio.Reader): The reader to check
+//
+// Returns:
+//
+//	bool: True if the input starts with a ptt CSV header
+func IsCSVRecordInput(reader *bufio.Reader) bool {
+	peek, _ := reader.Peek(512)
+	peek = bytes.TrimPrefix(peek, []byte("\xef\xbb\xbf"))
+	for _, header := range []string{"item,count\n", "item,count\r\n", "item,count,"} {
+		if bytes.HasPrefix(peek, []byte(header)) {
+			return true
+		}
+	}
+	return bytes.Equal(peek, []byte("item,count"))
+}
+
+// ReadCSVRecordsToMap reads ptt CSV output from a reader and returns a map of
+// words with the count of each record. Metadata columns are ignored and items
+// in the $HEX[...] format are decoded.
+//
+// Args:
+//
+//	reader (io.Reader): The reader of the CSV records
+//
+// Returns:
+//
+//	map[string]int: A map of words from the CSV input
+//	error: An error if the input is not ptt CSV output
+func ReadCSVRecordsToMap(reader io.Reader) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	bufferedReader := bufio.NewReader(reader)
+
+	// Skip a UTF-8 byte order mark if present
+	if bom, err := bufferedReader.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		bufferedReader.Discard(3)
+	}
+
+	csvReader := csv.NewReader(bufferedReader)
+	csvReader.ReuseRecord = true
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	} else if len(header) < 2 || header[0] != "item" || header[1] != "count" {
+		return nil, fmt.Errorf("missing item and count header")
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		count, err := strconv.Atoi(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid count %s for %s", record[1], record[0])
+		}
+		wordMap[DecodeHexString(record[0])] += count
+	}
+
+	return wordMap, nil
+}
+
+// ReadLinesToMap reads lines from a reader and returns a map of lines and
+// their frequency. Lines ending in CRLF are normalized and lines longer than
+// the maximum length are skipped.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to read lines from
+//	maxLineLength (int): The maximum length of a line in bytes
+//
+// Returns:
+//
+//	map[string]int: A map of lines from the reader
+//	error: An error if one occurred
+func ReadLinesToMap(reader io.Reader, maxLineLength int) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
+
+	scanner := NewLineScanner(reader, maxLineLength)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		wordMap[line]++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return wordMap, nil
+}
+
+// NewLineScanner returns a line scanner that accepts lines up to the maximum
+// length and strips any trailing carriage return from each line. Longer lines
+// are skipped and counted instead of stopping the scan.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to scan
+//	maxLineLength (int): The maximum length of a line in bytes
+//
+// Returns:
+//
+//	*bufio.Scanner: The line scanner
+func NewLineScanner(reader io.Reader, maxLineLength int) *bufio.Scanner {
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
+
+	// The buffer leaves room for a CRLF line ending so the limit only applies
+	// to the line itself
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, min(64*1024, maxLineLength+2)), maxLineLength+2)
+
+	// bufio.ScanLines drops the carriage return of CRLF line endings. A full
+	// buffer without a line ending is discarded up to the next newline.
+	skipping := false
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if skipping {
+			if index := bytes.IndexByte(data, '\n'); index >= 0 {
+				skipping = false
+				return index + 1, nil, nil
+			}
+			return len(data), nil, nil
+		}
+
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance == 0 && len(data) > maxLineLength+1 {
+			skippedLines.Add(1)
+			skipping = true
+			return len(data), nil, nil
+		} else if len(token) > maxLineLength {
+			skippedLines.Add(1)
+			return advance, nil, nil
+		}
+		return advance, token, err
+	})
+	return scanner
+}
+
+// GetSkippedLineCount returns the number of lines longer than the maximum
+// length that were skipped while reading input
+//
+// Args:
+//
+//	None
+//
+// Returns:
+//
+//	int64: The number of skipped lines
+func GetSkippedLineCount() int64 {
+	return skippedLines.Load()
+}
+
+// GetDecompressedReader detects gzip, bzip2, xz, and zstd compressed input by
+// magic bytes and returns a reader that streams the decompressed data. Input
+// that is not compressed is returned as is.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to detect compression on
+//
+// Returns:
+//
+//	io.Reader: A reader for the decompressed data
+//	error: An error if one occurred
+func GetDecompressedReader(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+	magic, err := buffered.Peek(6)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		fmt.Fprintf(os.Stderr, "[*] Detected gzip compressed input. Decompressing...\n")
+		return gzip.NewReader(buffered)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		fmt.Fprintf(os.Stderr, "[*] Detected bzip2 compressed input. Decompressing...\n")
+		return bzip2.NewReader(buffered), nil
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		fmt.Fprintf(os.Stderr, "[*] Detected xz compressed input. Decompressing...\n")
+		return xz.NewReader(buffered)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		fmt.Fprintf(os.Stderr, "[*] Detected zstd compressed input. Decompressing...\n")
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+
+	return buffered, nil
+}
+
+// GetDecodedReader returns a reader that transcodes the input from the named
+// encoding to UTF-8. The "auto" encoding detects the encoding from a byte
+// order mark or the first bytes of the input and an empty encoding returns
+// the input as is. Input detected as UTF-8 falls back to Windows-1252 for any
+// invalid UTF-8 found after the first bytes.
+//
+// Args:
+//
+//	reader (io.Reader): The reader to transcode
+//	inputEncoding (string): The name of the encoding such as latin1,
+//	windows-1252, utf-16le, or shift_jis
+//
+// Returns:
+//
+//	io.Reader: A reader for the UTF-8 data
+//	error: An error if the encoding is not supported
+func GetDecodedReader(reader io.Reader, inputEncoding string) (io.Reader, error) {
+	if inputEncoding == "" {
+		return reader, nil
+	}
+
+	buffered := bufio.NewReader(reader)
+	if strings.EqualFold(inputEncoding, "auto") {
+		sample, err := buffered.Peek(4096)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		// Drop a rune that may be cut off at the end of a full sample
+		if err == nil {
+			for i := len(sample) - 1; i >= 0 && i > len(sample)-utf8.UTFMax; i-- {
+				if utf8.RuneStart(sample[i]) {
+					if !utf8.FullRune(sample[i:]) {
+						sample = sample[:i]
+					}
+					break
+				}
+			}
+		}
+
+		inputEncoding = DetectEncoding(sample)
+		if inputEncoding == "utf-8" {
+			return transform.NewReader(buffered, textunicode.BOMOverride(&utf8FallbackDecoder{})), nil
+		}
+		fmt.Fprintf(os.Stderr, "[*] Detected %s encoded input. Transcoding...\n", inputEncoding)
+	}
+
+	decoding, err := htmlindex.Get(inputEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported encoding %s", inputEncoding)
+	}
+
+	// A byte order mark takes precedence over the named encoding
+	return transform.NewReader(buffered, textunicode.BOMOverride(decoding.NewDecoder())), nil
+}
+
+// utf8FallbackDecoder passes valid UTF-8 through and decodes every byte that
+// is not part of a valid UTF-8 sequence as Windows-1252. It is used for auto
+// detected UTF-8 input where legacy bytes can appear after the sample.
+type utf8FallbackDecoder struct {
+	transform.NopResetter
+	notified bool
+}
+
+// Transform implements the transform.Transformer interface
+func (d *utf8FallbackDecoder) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	nDst, nSrc := 0, 0
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			if !d.notified {
+				fmt.Fprintf(os.Stderr, "[*] Detected invalid UTF-8 in input. Transcoding it as windows-1252...\n")
+				d.notified = true
+			}
+
+			r = charmap.Windows1252.DecodeByte(src[nSrc])
+			if nDst+utf8.RuneLen(r) > len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], r)
+			nSrc++
+			continue
+		}
+
+		if nDst+size > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		nSrc += size
+	}
+
+	return nDst, nSrc, nil
+}
+
+// DetectEncoding guesses the encoding of a sample of input from a byte order
+// mark, valid UTF-8, the position of zero bytes for UTF-16, and valid
+// Shift-JIS double byte sequences. Windows-1252 is returned otherwise.
+//
+// Args:
+//
+//	sample ([]byte): The first bytes of the input
+//
+// Returns:
+//
+//	string: The name of the detected encoding
+func DetectEncoding(sample []byte) string {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8"
+	case bytes.HasPrefix(sample, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(sample, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+
+	// Count zero bytes in even and odd positions for UTF-16 without a BOM
+	evenZeros, oddZeros := 0, 0
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	if len(sample) >= 2 && oddZeros*10 > len(sample)*3 && evenZeros < oddZeros/4 {
+		return "utf-16le"
+	} else if len(sample) >= 2 && evenZeros*10 > len(sample)*3 && oddZeros < evenZeros/4 {
+		return "utf-16be"
+	}
+
+	if utf8.Valid(sample) {
+		return "utf-8"
+	}
+
+	if IsShiftJIS(sample) {
+		return "shift_jis"
+	}
+
+	return "windows-1252"
+}
+
+// IsShiftJIS returns true if every high byte in the sample is part of a valid
+// Shift-JIS double byte sequence or a half-width katakana. Most Japanese text
+// uses trail bytes above 0x7f so at least half of the sequences must as well
+// to avoid matching Latin text.
+//
+// Args:
+//
+//	sample ([]byte): The bytes to check
+//
+// Returns:
+//
+//	bool: True if the sample looks like Shift-JIS
+func IsShiftJIS(sample []byte) bool {
+	doubleBytes, highTrails := 0, 0
+	for i := 0; i < len(sample); i++ {
+		b := sample[i]
+		switch {
+		case b < 0x80 || (b >= 0xa1 && b <= 0xdf):
+			continue
+		case (b >= 0x81 && b <= 0x9f) || (b >= 0xe0 && b <= 0xef):
+			if i+1 == len(sample) {
+				return false
+			}
+			trail := sample[i+1]
+			if trail < 0x40 || trail > 0xfc || trail == 0x7f {
+				return false
+			}
+			if trail >= 0x80 {
+				highTrails++
+			}
+			doubleBytes++
+			i++
+		default:
+			return false
+		}
+	}
+
+	return doubleBytes > 0 && highTrails*2 >= doubleBytes
+}
+
+// archiveExtensions are the file extensions read as zip or tar archives
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz", ".tar.zst", ".tzst"}
+
+// IsArchiveFile checks if a path is a zip or tar archive by its extension
+//
+// Args:
+//
+//	path (string): The path to check
+//
+// Returns:
+//
+//	bool: True if the path is an archive
+func IsArchiveFile(path string) bool {
+	name := strings.ToLower(path)
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchArchiveGlobs checks a directory or archive member name against include
+// and exclude glob patterns. Patterns match either the full name or the base
+// name and members must match an include pattern if any are provided.
+//
+// Args:
+//
+//	name (string): The name of the member
+//	include ([]string): Glob patterns of members to read
+//	exclude ([]string): Glob patterns of members to skip
+//
+// Returns:
+//
+//	bool: True if the member should be read
+func MatchArchiveGlobs(name string, include []string, exclude []string) bool {
+	name = filepath.ToSlash(name)
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(name)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(include) > 0 && !matches(include) {
+		return false
+	}
+
+	return !matches(exclude)
+}
+
+// WalkArchive calls a function with the name and contents of every regular
+// file in a zip or tar archive that matches the glob patterns. Compressed tar
+// archives are detected by magic bytes.
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to open the archive from (used for testing)
+//	archive (string): The path of the archive
+//	include ([]string): Glob patterns of members to read
+//	exclude ([]string): Glob patterns of members to skip
+//	fn (func(string, io.Reader) error): The function to call for each member
+//
+// Returns:
+//
+//	error: An error if one occurred
+func WalkArchive(fs models.FileSystem, archive string, include []string, exclude []string, fn func(name string, reader io.Reader) error) error {
+	file, err := fs.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		// zip needs random access so read the archive into memory if the file
+		// cannot provide it
+		var readerAt io.ReaderAt
+		var size int64
+		if osFile, ok := file.(*os.File); ok {
+			info, err := osFile.Stat()
+			if err != nil {
+				return err
+			}
+			readerAt, size = osFile, info.Size()
+		} else {
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return err
+			}
+			readerAt, size = bytes.NewReader(data), int64(len(data))
+		}
+
+		zipReader, err := zip.NewReader(readerAt, size)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range zipReader.File {
+			if member.FileInfo().IsDir() || !MatchArchiveGlobs(member.Name, include, exclude) {
+				continue
+			}
+
+			reader, err := member.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(archive+"/"+member.Name, reader)
+			reader.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	reader, err := GetDecompressedReader(file)
+	if err != nil {
+		return err
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg || !MatchArchiveGlobs(header.Name, include, exclude) {
+			continue
+		}
+
+		if err := fn(archive+"/"+header.Name, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// LoadStdinToMap reads the contents of stdin and returns a map[string]int
+// where the key is the line and the value is the frequency of the line
+// in the input
+//
+// Args:
+//
+//	scanner (models.Scanner): The scanner to read from stdin
+//
+// Returns:
+//
+//	map[string]int: A map of lines from stdin
+//	error: An error if one occurred
+func LoadStdinToMap(scanner models.Scanner) (map[string]int, error) {
+	m := make(map[string]int)
+	pttInput := false
+	line0 := false
+	reDetect := regexp.MustCompile(`^\d+\s(\w+|\W+)$`)
+	reParse := regexp.MustCompile(`^\d+`)
+
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+
+		// Detect ptt -v output
+		if matched := reDetect.MatchString(scanner.Text()); matched && pttInput == false && line0 == false {
+			fmt.Fprintf(os.Stderr, "[*] Detected ptt -v output. Importing...\n")
+			pttInput = true
+		}
+
+		if pttInput {
+			line := scanner.Text()
+			match := reParse.FindString(line)
+			value, err := strconv.Atoi(match)
+			if err != nil {
+				return nil, err
+			}
+			newLine := strings.TrimSpace(strings.Replace(line, match, "", 1))
+			m[newLine] += value
+
+		} else {
+			line := scanner.Text()
+			m[line]++
+		}
+		line0 = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ReadURLsToMap reads the contents of the multiple URLs and returns a map of words
+// from the URLs. Supports files or directories containing URLs and crawling
+// same-site links from the URLs. The words of each URL are also counted in
+// the sources map if one is given, with crawled pages credited to the URL the
+// crawl reached them from.
+//
+// Args:
+//
+//	urls ([]string): The URLs to read
+//	parsingMode (int): Change parsing mode for URL input. [0 = Strict, 1 = Permissive, 2 = Maximum] [0-2].
+//	debugMode (int): A flag to print debug information
+//	include ([]string): Glob patterns of directory and archive members to read
+//	exclude ([]string): Glob patterns of directory and archive members to skip
+//	crawl (models.CrawlOptions): The options to crawl same-site links from the
+//	URLs instead of fetching only the given URLs
+//	extract (models.ExtractOptions): The HTML sources and JSON paths to
+//	extract from responses
+//	sources (map[string]map[string]int): A map of URLs to fill with the words
+//	from each URL, or nil to not track the source of words
+//
+// Returns:
+//
+//	map[string]int: A map of words from the URLs
+//	error: An error if one occurred
+func ReadURLsToMap(urls []string, parsingMode int, debugMode int, include []string, exclude []string, crawl models.CrawlOptions, extract models.ExtractOptions, sources map[string]map[string]int) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	var wg sync.WaitGroup
+
+	ch := make(chan string)
+	done := make(chan bool)
+
+	go func() {
+		for word := range ch {
+			wordMap[word]++
+		}
+		done <- true
+	}()
+
+	resetURLStats()
+	defer printURLStats()
+
+	if crawl.Enabled() {
+		seeds, err := CollectURLs(urls, include, exclude)
+		if err == nil {
+			err = CrawlURLs(seeds, parsingMode, debugMode, crawl, extract, ch, sources)
+		}
+
+		close(ch)
+		<-done
+		if err != nil {
+			return nil, err
+		}
+
+		delete(wordMap, "")
+		for _, sourceMap := range sources {
+			delete(sourceMap, "")
+		}
+		return wordMap, nil
+	}
+
+	// Fetch each URL through its own channel to count its words when sources
+	// are tracked
+	if sources != nil {
+		seeds, err := CollectURLs(urls, include, exclude)
+		if err != nil {
+			close(ch)
+			<-done
+			return nil, err
+		}
+
+		var mutex sync.Mutex
+		for _, seed := range seeds {
+			wg.Add(1)
+			go func(seed string) {
+				defer wg.Done()
+				seedMap := make(map[string]int)
+				seedCh := make(chan string)
+
+				go func() {
+					var seedWG sync.WaitGroup
+					seedWG.Add(1)
+					ProcessURL(seed, seedCh, &seedWG, parsingMode, debugMode, extract)
+					close(seedCh)
+				}()
+
+				for word := range seedCh {
+					seedMap[word]++
+					ch <- word
+				}
+				delete(seedMap, "")
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				sources[seed] = CombineMaps(sources[seed], seedMap)
+			}(seed)
+		}
+
+		wg.Wait()
+		close(ch)
+		<-done
+
+		delete(wordMap, "")
+		return wordMap, nil
+	}
+
+	for _, iURL := range urls {
+		if IsValidURL(iURL) {
+			wg.Add(1)
+			go ProcessURL(iURL, ch, &wg, parsingMode, debugMode, extract)
+
+		} else if IsFileSystemDirectory(iURL) {
+			files, err := GetFilesInDirectory(iURL)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if !MatchArchiveGlobs(file, include, exclude) {
+					continue
+				}
+				wg.Add(1)
+				go ProcessURLFile(file, ch, &wg, parsingMode, debugMode, extract)
+			}
+		} else if IsArchiveFile(iURL) {
+			err := WalkArchive(&models.RealFileSystem{}, iURL, include, exclude, func(name string, reader io.Reader) error {
+				scanner := bufio.NewScanner(reader)
+				for scanner.Scan() {
+					line := scanner.Text()
+					if IsValidURL(line) {
+						wg.Add(1)
+						go ProcessURL(line, ch, &wg, parsingMode, debugMode, extract)
+					} else {
+						fmt.Fprintf(os.Stderr, "[!] Rejected URL: %s.\n", line)
+					}
+				}
+				return scanner.Err()
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else if IsValidFile(iURL) {
+			wg.Add(1)
+			go ProcessURLFile(iURL, ch, &wg, parsingMode, debugMode, extract)
+		} else {
+			fmt.Fprintf(os.Stderr, "[!] Rejected URL or file: %s.\n", iURL)
+			return nil, fmt.Errorf("invalid input: %s", iURL)
+		}
+	}
+
+	wg.Wait()
+	close(ch)
+	<-done
+
+	delete(wordMap, "")
+
+	return wordMap, nil
+}
+
+// ReadDocumentsToMap extracts text from local HTML, DOCX, XLSX, PDF, email,
+// WARC, and text documents and returns a map of sentences and phrases parsed
+// with the same parsing modes as URLs. Supports files, directories, and
+// archives of documents.
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to read the files from (used for testing)
+//	filenames ([]string): The documents to read
+//	parsingMode (int): Change parsing mode for document input. [0 = Strict,
+//	1 = Permissive, 2 = Maximum] [0-2].
+//	debugMode (int): A flag to print debug information
+//	include ([]string): Glob patterns of directory and archive members to read
+//	exclude ([]string): Glob patterns of directory and archive members to skip
+//	extract (models.ExtractOptions): The HTML sources and JSON paths to
+//	extract from documents
+//
+// Returns:
+//
+//	map[string]int: A map of words from the documents
+//	error: An error if one occurred
+func ReadDocumentsToMap(fs models.FileSystem, filenames []string, parsingMode int, debugMode int, include []string, exclude []string, extract models.ExtractOptions) (map[string]int, error) {
+	wordMap := make(map[string]int)
+	ch := make(chan string)
+	done := make(chan bool)
+
+	go func() {
+		for word := range ch {
+			wordMap[word]++
+		}
+		done <- true
+	}()
+
+	processDocument := func(name string, reader io.Reader) error {
+		reader, err := GetDecompressedReader(reader)
+		if err != nil {
+			return err
+		}
+
+		// Detect the document type without any compression extension
+		name = TrimCompressionExtension(name)
+
+		// Stream WARC files record by record and parse each response like a
+		// live URL
+		buffered := bufio.NewReader(reader)
+		if header, _ := buffered.Peek(5); document.GetDocumentType(name, header) == "warc" {
+			return document.WalkWARC(buffered, func(uri string, contentType string, body []byte) error {
+				lines, values, err := ExtractResponseLines(contentType, body, extract)
+				if err != nil {
+					return nil
+				}
+
+				if debugMode >= 1 {
+					fmt.Fprintf(os.Stderr, "[?] URL: %s\n", uri)
+					fmt.Fprintf(os.Stderr, "[?] Content-Type: %s\n", contentType)
+					fmt.Fprintf(os.Stderr, "[?] Line Count: %d\n", len(lines))
+				}
+
+				ParseLinesToChannel(lines, IsExtractedContentType(contentType), parsingMode, extract.Punctuation, ch)
+				for _, value := range values {
+					ch <- value
+				}
+				return nil
+			})
+		}
+
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return err
+		}
+
+		lines, values, err := document.ExtractDocumentText(name, data, extract)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+
+		if debugMode >= 1 {
+			fmt.Fprintf(os.Stderr, "[?] Document: %s\n", name)
+			fmt.Fprintf(os.Stderr, "[?] Document Type: %s\n", document.GetDocumentType(name, data))
+			fmt.Fprintf(os.Stderr, "[?] Line Count: %d\n", len(lines))
+		}
+
+		ParseLinesToChannel(lines, true, parsingMode, extract.Punctuation, ch)
+		for _, value := range values {
+			ch <- value
+		}
+		return nil
+	}
+
+	var err error
+	for i := 0; i < len(filenames) && err == nil; i++ {
+		filename := filenames[i]
+		if IsFileSystemDirectory(filename) {
+			files, dirErr := GetFilesInDirectory(filename)
+			if dirErr != nil {
+				err = dirErr
+				break
+			}
+			for _, file := range files {
+				if MatchArchiveGlobs(file, include, exclude) {
+					filenames = append(filenames, file)
+				}
+			}
+		} else if IsArchiveFile(filename) {
+			err = WalkArchive(fs, filename, include, exclude, processDocument)
+		} else {
+			file, openErr := fs.Open(filename)
+			if openErr != nil {
+				err = openErr
+				break
+			}
+			err = processDocument(filename, file)
+			file.Close()
+		}
+	}
+
+	close(ch)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+
+	delete(wordMap, "")
+
+	return wordMap, nil
+}
+
+// potfileHashFormats are the hash formats recognized when splitting potfile
+// lines. The patterns do not allow a colon unless the format requires one so
+// the hash and plaintext can be split on the last valid separator. Salted
+// formats store the salt after the hash separated by a colon. Unsalted 32
+// character hashes are labelled md5/ntlm because the two can not be told
+// apart without the hash mode.
+var potfileHashFormats = []struct {
+	Name    string
+	Pattern *regexp.Regexp
+}{
+	{"mysql323", regexp.MustCompile(`^[a-fA-F0-9]{16}$`)},
+	{"md5/ntlm", regexp.MustCompile(`^[a-fA-F0-9]{32}$`)},
+	{"sha1", regexp.MustCompile(`^[a-fA-F0-9]{40}$`)},
+	{"sha224", regexp.MustCompile(`^[a-fA-F0-9]{56}$`)},
+	{"sha256", regexp.MustCompile(`^[a-fA-F0-9]{64}$`)},
+	{"sha384", regexp.MustCompile(`^[a-fA-F0-9]{96}$`)},
+	{"sha512", regexp.MustCompile(`^[a-fA-F0-9]{128}$`)},
+	{"md5-salted", regexp.MustCompile(`^[a-fA-F0-9]{32}:[^:]*$`)},
+	{"sha1-salted", regexp.MustCompile(`^[a-fA-F0-9]{40}:[^:]*$`)},
+	{"sha224-salted", regexp.MustCompile(`^[a-fA-F0-9]{56}:[^:]*$`)},
+	{"sha256-salted", regexp.MustCompile(`^[a-fA-F0-9]{64}:[^:]*$`)},
+	{"sha384-salted", regexp.MustCompile(`^[a-fA-F0-9]{96}:[^:]*$`)},
+	{"sha512-salted", regexp.MustCompile(`^[a-fA-F0-9]{128}:[^:]*$`)},
+	{"netntlmv1", regexp.MustCompile(`^[^:]+::[^:]*:[a-fA-F0-9]{48}:[a-fA-F0-9]{48}:[a-fA-F0-9]{16}$`)},
+	{"netntlmv2", regexp.MustCompile(`^[^:]+::[^:]*:[a-fA-F0-9]{16}:[a-fA-F0-9]{32}:[a-fA-F0-9]+$`)},
+	{"crypt", regexp.MustCompile(`^\$[a-zA-Z0-9_-]+\$[^:]*$`)},
+}
+
+// potfileCryptNames are the hash type names of common modular crypt and John
+// the Ripper tags
+var potfileCryptNames = map[string]string{
+	"1":         "md5crypt",
+	"2":         "bcrypt",
+	"2a":        "bcrypt",
+	"2b":        "bcrypt",
+	"2x":        "bcrypt",
+	"2y":        "bcrypt",
+	"5":         "sha256crypt",
+	"6":         "sha512crypt",
+	"apr1":      "apr1",
+	"y":         "yescrypt",
+	"NT":        "ntlm",
+	"LM":        "lm",
+	"krb5tgs":   "krb5tgs",
+	"krb5asrep": "krb5asrep",
+}
+
+// ParsePotfileLine splits a hashcat potfile or John the Ripper pot line into
+// the hash, hash type, and plaintext. The line is split on the last separator
+// that leaves a recognized hash and otherwise on the last colon. Raw hashes
+// followed by two or more colons are read as hash:salt:plaintext so the salt
+// is not kept in the plaintext, which means plaintext containing a colon is
+// only kept whole when it is in the $HEX[...] format. Plaintext in the
+// $HEX[...] format is decoded.
+//
+// Args:
+//
+//	line (string): The potfile line to parse
+//
+// Returns:
+//
+//	hash (string): The hash portion of the line
+//	hashType (string): The detected hash type or "unknown"
+//	plaintext (string): The decoded plaintext
+//	ok (bool): False if the line does not contain a separator
+func ParsePotfileLine(line string) (hash string, hashType string, plaintext string, ok bool) {
+	separator := -1
+	hashType = "unknown"
+
+	for i := len(line) - 1; i >= 0; i-- {
+		if line[i] != ':' {
+			continue
+		}
+		if separator == -1 {
+			separator = i
+		}
+
+		if name := DetectPotfileHashType(line[:i]); name != "" {
+			separator = i
+			hashType = name
+			break
+		}
+	}
+
+	if separator == -1 {
+		return "", "", "", false
+	}
+
+	hash = line[:separator]
+	plaintext = line[separator+1:]
+
+	return hash, hashType, DecodeHexString(plaintext), true
+}
+
+// DetectPotfileHashType returns the name of the hash format of a potfile hash
+// or an empty string if the format is not recognized
+//
+// Args:
+//
+//	hash (string): The hash to identify
+//
+// Returns:
+//
+//	string: The name of the hash format
+func DetectPotfileHashType(hash string) string {
+	for _, format := range potfileHashFormats {
+		if !format.Pattern.MatchString(hash) {
+			continue
+		}
+
+		if format.Name == "crypt" {
+			tag := strings.Split(hash, "$")[1]
+			if name, ok := potfileCryptNames[tag]; ok {
+				return name
+			} else if strings.HasPrefix(tag, "dynamic_") {
+				return tag
+			}
+		}
+
+		return format.Name
+	}
+
+	return ""
+}
+
+// ParsePotfileMap parses a map of hashcat potfile or John the Ripper pot lines
+// and returns a new map of plaintexts. Lines without a separator are dropped.
+// The hash types of each plaintext are recorded separately so the plaintext
+// is not changed.
+//
+// Args:
+//
+//	input (map[string]int): A map of potfile lines
+//	hashTypes (map[string][]string): The hash types of each plaintext to add
+//	to or nil to not record hash types
+//
+// Returns:
+//
+//	map[string]int: A new map of plaintexts
+func ParsePotfileMap(input map[string]int, hashTypes map[string][]string) map[string]int {
+	output := make(map[string]int)
+
+	for line, count := range input {
+		_, hashType, plaintext, ok := ParsePotfileLine(line)
+		if !ok || plaintext == "" {
+			continue
+		}
+
+		if hashTypes != nil && !slices.Contains(hashTypes[plaintext], hashType) {
+			hashTypes[plaintext] = append(hashTypes[plaintext], hashType)
+		}
+		output[plaintext] += count
+	}
+
+	return output
+}
+
+// ntHashPattern matches the NT hash field of credential dump lines
+var ntHashPattern = regexp.MustCompile(`^[a-fA-F0-9]{32}$`)
+
+// historyUserPattern matches the usernames of secretsdump -history lines
+var historyUserPattern = regexp.MustCompile(`_history\d+$`)
+
+// ParseCredentialLine parses a pwdump, NTDS, or secretsdump line in the
+// DOMAIN\user:rid:lm:nt::: format. Any trailing secretsdump status is ignored.
+// Password history lines such as user_history0 are not accounts and are
+// skipped.
+//
+// Args:
+//
+//	line (string): The line to parse
+//
+// Returns:
+//
+//	models.Credential: The parsed account
+//	bool: False if the line is not in the expected format or is a password
+//	history line
+func ParseCredentialLine(line string) (models.Credential, bool) {
+	var credential models.Credential
+
+	parts := strings.Split(line, ":")
+	if len(parts) < 7 || parts[0] == "" || historyUserPattern.MatchString(parts[0]) {
+		return credential, false
+	}
+
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return credential, false
+	}
+
+	if !ntHashPattern.MatchString(parts[3]) {
+		return credential, false
+	}
+
+	credential.Username = parts[0]
+	if index := strings.LastIndex(parts[0], "\\"); index != -1 {
+		credential.Domain = parts[0][:index]
+		credential.Username = parts[0][index+1:]
+	}
+	credential.RID = parts[1]
+	credential.LMHash = strings.ToLower(parts[2])
+	credential.NTHash = strings.ToLower(parts[3])
+
+	return credential, true
+}
+
+// ParseCredentialMap parses a map of pwdump, NTDS, or secretsdump lines and
+// returns the accounts sorted by domain and username. Lines that are not in
+// the expected format are dropped.
+//
+// Args:
+//
+//	input (map[string]int): A map of credential dump lines
+//
+// Returns:
+//
+//	[]models.Credential: The parsed accounts
+func ParseCredentialMap(input map[string]int) []models.Credential {
+	credentials := make([]models.Credential, 0, len(input))
+
+	for line := range input {
+		if credential, ok := ParseCredentialLine(line); ok {
+			credentials = append(credentials, credential)
+		}
+	}
+
+	sort.Slice(credentials, func(i, j int) bool {
+		if credentials[i].Domain != credentials[j].Domain {
+			return credentials[i].Domain < credentials[j].Domain
+		}
+		return credentials[i].Username < credentials[j].Username
+	})
+
+	return credentials
+}
+
+// JoinCredentialsWithPotfile joins accounts with cracked NT hashes from
+// hashcat potfiles or John the Ripper pot files
+//
+// Args:
+//
+//	credentials ([]models.Credential): The accounts to join
+//	potfile (map[string]int): A map of potfile lines
+//
+// Returns:
+//
+//	[]models.Credential: The accounts with cracked plaintext filled in
+func JoinCredentialsWithPotfile(credentials []models.Credential, potfile map[string]int) []models.Credential {
+	cracked := make(map[string]string)
+	for line := range potfile {
+		hash, _, plaintext, ok := ParsePotfileLine(line)
+		if !ok {
+			continue
+		}
+		hash = strings.ToLower(strings.TrimPrefix(hash, "$NT$"))
+		cracked[hash] = plaintext
+	}
+
+	for i, credential := range credentials {
+		if plaintext, ok := cracked[credential.NTHash]; ok {
+			credentials[i].Plaintext = plaintext
+			credentials[i].Cracked = true
+		}
+	}
+
+	return credentials
+}
+
+// CredentialsToMap returns a map of cracked plaintext and the number of
+// accounts using each plaintext
+//
+// Args:
+//
+//	credentials ([]models.Credential): The accounts to count
+//
+// Returns:
+//
+//	map[string]int: A map of plaintext frequencies
+func CredentialsToMap(credentials []models.Credential) map[string]int {
+	output := make(map[string]int)
+
+	for _, credential := range credentials {
+		if credential.Cracked && credential.Plaintext != "" {
+			output[credential.Plaintext]++
+		}
+	}
+
+	return output
+}
+
+// AddProvenance records a source for every item of a map so the sources
+// that produced each item can be reported. Sources are only recorded once
+// per item.
+//
+// Args:
+//
+//	provenance (map[string][]string): The sources of each item to add to
+//	items (map[string]int): The items produced by the source
+//	source (string): The name of the source such as a file path or URL
+//
+// Returns:
+//
+//	None
+func AddProvenance(provenance map[string][]string, items map[string]int, source string) {
+	for item := range items {
+		known := false
+		for _, existing := range provenance[item] {
+			known = known || existing == source
+		}
+		if !known {
+			provenance[item] = append(provenance[item], source)
+		}
+	}
+}
+
+// NormalizedTotal is the smallest total that the counts of a source are scaled
+// to when sources are normalized
+const NormalizedTotal = 1000000
+
+// GetNormalizedTotal returns the common total to normalize sources to. The
+// total is NormalizedTotal or the size of the largest source if it is larger
+// so no item of any source is scaled below a count of 1. The size of a source
+// is its total count in the count mode and its number of items in the rank
+// mode.
+//
+// Args:
+//
+//	sources ([]map[string]int): The items of each source
+//	mode (string): The normalization mode [count, rank]
+//
+// Returns:
+//
+//	int: The total to normalize every source to
+func GetNormalizedTotal(sources []map[string]int, mode string) int {
+	total := NormalizedTotal
+	for _, source := range sources {
+		size := len(source)
+		if mode == "count" {
+			size = 0
+			for _, count := range source {
+				size += count
+			}
+		}
+		total = max(total, size)
+	}
+
+	return total
+}
+
+// NormalizeMap scales the counts of a source so sources of different sizes
+// contribute equally. The count mode scales counts by their share of the
+// total count and the rank mode scores items by their frequency rank so the
+// most frequent item scores the total. Every source should use the same total
+// from GetNormalizedTotal.
+//
+// Args:
+//
+//	input (map[string]int): The items of the source
+//	mode (string): The normalization mode [count, rank]
+//	total (int): The total to scale the counts to
+//
+// Returns:
+//
+//	map[string]int: A new map of normalized counts
+//	error: An error if the mode is not known
+func NormalizeMap(input map[string]int, mode string, total int) (map[string]int, error) {
+	output := make(map[string]int, len(input))
+
+	switch mode {
+	case "count":
+		size := 0
+		for _, count := range input {
+			size += count
+		}
+		for item, count := range input {
+			output[item] = int(math.Round(float64(count) * float64(total) / float64(size)))
+		}
+	case "rank":
+		counts := make([]int, 0, len(input))
+		for _, count := range input {
+			counts = append(counts, count)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+
+		// Items with the same count share the highest rank of the count
+		ranks := make(map[int]int)
+		for i, count := range counts {
+			if _, ok := ranks[count]; !ok {
+				ranks[count] = i
+			}
+		}
+		for item, count := range input {
+			score := float64(len(counts)-ranks[count]) / float64(len(counts))
+			output[item] = int(math.Round(score * float64(total)))
+		}
+	default:
+		return nil, fmt.Errorf("unknown normalization mode %s", mode)
+	}
+
+	return output, nil
+}
+
+// GetWeightScale returns the factor to multiply every source by so the
+// smallest weight becomes 1. Scaling all sources by the same factor keeps
+// their relative weights and stops low weights from rounding counts to 0.
+//
+// Args:
+//
+//	weights ([]float64): The weights of the weighted sources
+//
+// Returns:
+//
+//	float64: The factor to multiply the weight of every source by
+func GetWeightScale(weights []float64) float64 {
+	scale := 1.0
+	for _, weight := range weights {
+		if weight > 0 {
+			scale = max(scale, 1/weight)
+		}
+	}
+
+	return scale
+}
+
+// WeightMap multiplies the counts of a source by its weight. Weights below 1
+// can round counts to 0, so weights should be scaled with GetWeightScale.
+//
+// Args:
+//
+//	input (map[string]int): The items of the source
+//	weight (float64): The weight of the source
+//
+// Returns:
+//
+//	map[string]int: A new map of weighted counts
+func WeightMap(input map[string]int, weight float64) map[string]int {
+	output := make(map[string]int, len(input))
+	for item, count := range input {
+		output[item] = int(math.Round(float64(count) * weight))
+	}
+
+	return output
+}
+
+// CombineMaps combines any number of maps into a single map combining values for common keys
+// and returning a new map
+//
+// Args:
+// maps ([]map[string]int): The maps to combine
+//
+// Returns:
+// map[string]int: A new map combining the values of the input maps
+func CombineMaps(maps ...map[string]int) map[string]int {
+	var result sync.Map
+
+	for _, m := range maps {
+		for k, v := range m {
+			if val, ok := result.Load(k); ok {
+				result.Store(k, val.(int)+v)
+			} else {
+				result.Store(k, v)
+			}
+		}
+	}
+
+	finalResult := make(map[string]int)
+	result.Range(func(k, v interface{}) bool {
+		finalResult[k.(string)] = v.(int)
+		return true
+	})
+
+	return finalResult
+}
+
+// DefaultHTTPTimeout is the default number of seconds to wait for a URL
+// request before giving up
+const DefaultHTTPTimeout = 30
+
+// httpClient is the client used for URL requests and httpOptions are the
+// options it was configured with by ConfigureHTTPClient
+var httpClient = &http.Client{Timeout: DefaultHTTPTimeout * time.Second}
+var httpOptions = models.HTTPOptions{Timeout: DefaultHTTPTimeout}
+
+// ConfigureHTTPClient sets the HTTP client, rate limits, and cache used for all
+// URL requests
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to read the cookie file from (used for
+//	testing)
+//	options (models.HTTPOptions): The settings of the client
+//
+// Returns:
+//
+//	error: An error if one occurred
+func ConfigureHTTPClient(fs models.FileSystem, options models.HTTPOptions) error {
+	for _, header := range options.Headers {
+		if _, _, err := ParseHTTPHeader(header); err != nil {
+			return err
+		}
+	}
+
+	if options.Offline && options.CacheDir == "" {
+		return fmt.Errorf("offline mode requires a cache directory")
+	}
+	if options.CacheDir != "" {
+		if err := os.MkdirAll(options.CacheDir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	client, err := NewHTTPClient(fs, options)
+	if err != nil {
+		return err
+	}
+
+	httpClient = client
+	httpOptions = options
+	httpLimiter = newHostRateLimiter(options.RequestsPerSecond, options.Concurrency)
+	return nil
+}
+
+// NewHTTPClient creates an HTTP client with a proxy, cookie jar, TLS
+// verification setting, and timeout
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to read the cookie file from (used for
+//	testing)
+//	options (models.HTTPOptions): The settings of the client
+//
+// Returns:
+//
+//	*http.Client: The configured client
+//	error: An error if one occurred
+func NewHTTPClient(fs models.FileSystem, options models.HTTPOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.Proxy != "" {
+		proxy, err := url.Parse(options.Proxy)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %s", options.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if options.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(options.Timeout) * time.Second,
+	}
+
+	if options.CookieFile != "" {
+		jar, err := LoadCookieJar(fs, options.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		client.Jar = jar
+	}
+
+	return client, nil
+}
+
+// ParseHTTPHeader splits a "Name: value" header argument
+//
+// Args:
+//
+//	header (string): The header argument
+//
+// Returns:
+//
+//	string: The name of the header
+//	string: The value of the header
+//	error: An error if the header is not valid
+func ParseHTTPHeader(header string) (string, string, error) {
+	name, value, found := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %s", header)
+	}
+
+	return name, strings.TrimSpace(value), nil
+}
+
+// LoadCookieJar reads a Netscape format cookies.txt file as exported by
+// browsers and curl into a cookie jar. Lines of "name=value; name=value"
+// pairs for a URL are also accepted as "URL<tab>cookies".
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to read the file from (used for testing)
+//	filename (string): The name of the cookie file
+//
+// Returns:
+//
+//	http.CookieJar: The cookie jar with the cookies from the file
+//	error: An error if one occurred
+func LoadCookieJar(fs models.FileSystem, filename string) (http.CookieJar, error) {
+	data, err := fs.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for number, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		// Netscape files mark HttpOnly cookies with a comment prefix
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		switch len(fields) {
+		case 7:
+			domain := strings.TrimPrefix(fields[0], ".")
+			scheme := "http"
+			if strings.EqualFold(fields[3], "TRUE") {
+				scheme = "https"
+			}
+			cookie := &http.Cookie{
+				Name:     fields[5],
+				Value:    fields[6],
+				Path:     fields[2],
+				Secure:   scheme == "https",
+				HttpOnly: httpOnly,
+			}
+			if strings.EqualFold(fields[1], "TRUE") {
+				cookie.Domain = domain
+			}
+			if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+				cookie.Expires = time.Unix(expires, 0)
+			}
+			jar.SetCookies(&url.URL{Scheme: scheme, Host: domain, Path: fields[2]}, []*http.Cookie{cookie})
+		case 2:
+			target, err := url.Parse(fields[0])
+			if err != nil || target.Host == "" {
+				return nil, fmt.Errorf("invalid cookie URL on line %d of %s", number+1, filename)
+			}
+			cookies, err := http.ParseCookie(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid cookies on line %d of %s", number+1, filename)
+			}
+			jar.SetCookies(target, cookies)
+		default:
+			return nil, fmt.Errorf("invalid cookie on line %d of %s", number+1, filename)
+		}
+	}
+
+	return jar, nil
+}
+
+// DefaultRequestsPerSecond is the default number of requests per second sent
+// to each host for URL input
+const DefaultRequestsPerSecond = 2.0
+
+// DefaultConcurrency is the default number of URL requests sent at once
+const DefaultConcurrency = 8
+
+// maxFetchRetries is the number of times a throttled URL request is retried
+// and maxRetryAfter is the longest Retry-After delay that is honored
+const maxFetchRetries = 3
+const maxRetryAfter = 5 * time.Minute
+
+// httpLimiter limits the rate and concurrency of all URL requests
+var httpLimiter = newHostRateLimiter(DefaultRequestsPerSecond, DefaultConcurrency)
+
+// hostRateLimiter limits URL requests with a token bucket for each host and a
+// cap on the number of requests in flight. It also counts request results for
+// the summary at the end of a run.
+type hostRateLimiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	buckets map[string]*hostBucket
+	slots   chan struct{}
+	stats   models.URLStats
+}
+
+// hostBucket is the token bucket of a host
+type hostBucket struct {
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// newHostRateLimiter creates a limiter that allows rate requests per second
+// to each host and concurrency requests at once. Zero disables either limit.
+func newHostRateLimiter(rate float64, concurrency int) *hostRateLimiter {
+	limiter := &hostRateLimiter{rate: rate, buckets: make(map[string]*hostBucket)}
+	if concurrency > 0 {
+		limiter.slots = make(chan struct{}, concurrency)
+	}
+	return limiter
+}
+
+// reserve takes a token from the bucket of the host and returns how long to
+// wait before sending the request
+func (l *hostRateLimiter) reserve(host string, now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	capacity := math.Max(1, l.rate)
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &hostBucket{tokens: capacity, last: now}
+		l.buckets[host] = bucket
+	}
+
+	var wait time.Duration
+	if l.rate > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+		bucket.last = now
+		bucket.tokens--
+		if bucket.tokens < 0 {
+			wait = time.Duration(-bucket.tokens / l.rate * float64(time.Second))
+		}
+	}
+
+	if paused := bucket.pausedUntil.Sub(now); paused > 0 {
+		wait += paused
+	}
+
+	return wait
+}
+
+// wait blocks until a request to the host is allowed
+func (l *hostRateLimiter) wait(host string) {
+	time.Sleep(l.reserve(host, time.Now()))
+	if l.slots != nil {
+		l.slots <- struct{}{}
+	}
+}
+
+// release frees the concurrency slot of a finished request
+func (l *hostRateLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// pause stops requests to a host for the delay after it throttled a request
+func (l *hostRateLimiter) pause(host string, delay time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &hostBucket{tokens: math.Max(1, l.rate), last: time.Now()}
+		l.buckets[host] = bucket
+	}
+	if until := time.Now().Add(delay); until.After(bucket.pausedUntil) {
+		bucket.pausedUntil = until
+	}
+}
+
+// throttled counts a URL request that was throttled at least once
+func (l *hostRateLimiter) throttled() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.stats.Throttled++
+}
+
+// record counts the result of a URL request
+func (l *hostRateLimiter) record(statusCode int, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err != nil || (statusCode != http.StatusOK && statusCode != http.StatusNotFound && statusCode != http.StatusNotModified) {
+		l.stats.Failed++
+	} else {
+		l.stats.Fetched++
+	}
+}
+
+// limitedBody releases the concurrency slot of a request when its body is
+// closed
+type limitedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the body and releases the concurrency slot
+func (b *limitedBody) Close() error {
+	b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
+
+// GetURLStats returns the counts of fetched, failed, and throttled URL
+// requests since the last call to ReadURLsToMap. Requests are counted as
+// throttled once however many times they are retried.
+//
+// Args:
+//
+//	None
+//
+// Returns:
+//
+//	models.URLStats: The counts of URL request results
+func GetURLStats() models.URLStats {
+	httpLimiter.mutex.Lock()
+	defer httpLimiter.mutex.Unlock()
+	return httpLimiter.stats
+}
+
+// resetURLStats clears the counts of URL request results
+func resetURLStats() {
+	httpLimiter.mutex.Lock()
+	defer httpLimiter.mutex.Unlock()
+	httpLimiter.stats = models.URLStats{}
+}
+
+// printURLStats prints the summary of URL request results if any URLs were
+// requested
+func printURLStats() {
+	stats := GetURLStats()
+	if stats.Fetched+stats.Failed+stats.Throttled > 0 {
+		fmt.Fprintf(os.Stderr, "[*] URL summary: %d fetched, %d failed, %d throttled.\n", stats.Fetched, stats.Failed, stats.Throttled)
+	}
+}
+
+// GetRetryDelay checks if a response throttled the request and returns how
+// long to wait before retrying. The Retry-After header is used when present
+// and otherwise the delay doubles with each attempt.
+//
+// Args:
+//
+//	resp (*http.Response): The response to check
+//	attempt (int): The number of the attempt starting at 0
+//
+// Returns:
+//
+//	time.Duration: The delay before retrying
+//	bool: True if the request was throttled
+func GetRetryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	if delay, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		return delay, true
+	}
+
+	return time.Duration(5<<attempt) * time.Second, true
+}
+
+// ParseRetryAfter parses a Retry-After header as a number of seconds or an
+// HTTP date. Delays are limited to five minutes.
+//
+// Args:
+//
+//	header (string): The value of the Retry-After header
+//	now (time.Time): The current time for HTTP dates
+//
+// Returns:
+//
+//	time.Duration: The delay before retrying
+//	bool: True if the header is valid
+func ParseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		delay = date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+	} else {
+		return 0, false
+	}
+
+	return min(delay, maxRetryAfter), true
+}
+
+// userAgents are the browser user agents randomly chosen for URL requests
+var userAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.96 Safari/537.36",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A372 Safari/604.1",
+	"Mozilla/5.0 (iPad; CPU OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A5341f Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 11; Pixel 4) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.181 Mobile Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
+	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Safari/605.1.15",
+}
+
+// FetchURL requests a URL with the HTTP client set by ConfigureHTTPClient.
+// Requests wait for the rate limit of the host and a free concurrency slot and
+// throttled requests are retried after the Retry-After delay. A random browser
+// user agent is used unless one is configured. When a cache directory is set
+// cached responses are revalidated with ETag and Last-Modified headers or read
+// without a request in offline mode. The caller is responsible for closing
+// the response body.
+//
+// Args:
+//
+//	rawURL (string): The URL to request
+//
+// Returns:
+//
+//	*http.Response: The response from the server or cache
+//	error: An error if one occurred
+func FetchURL(rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgents[rand.Intn(len(userAgents))])
+	if httpOptions.UserAgent != "" {
+		req.Header.Set("User-Agent", httpOptions.UserAgent)
+	}
+
+	for _, header := range httpOptions.Headers {
+		name, value, err := ParseHTTPHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else {
+			req.Header.Add(name, value)
+		}
+	}
+
+	var cached *http.Response
+	if httpOptions.CacheDir != "" {
+		cached, err = ReadCachedResponse(httpOptions.CacheDir, rawURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error reading cached response for %s: %s.\n", rawURL, err)
+		}
+	}
+
+	if httpOptions.Offline {
+		if cached == nil {
+			err := fmt.Errorf("%s is not in the cache", rawURL)
+			httpLimiter.record(0, err)
+			return nil, err
+		}
+		httpLimiter.record(cached.StatusCode, nil)
+		return cached, nil
+	}
+
+	// Revalidate cached responses instead of downloading them again
+	if cached != nil {
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := fetchWithLimits(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return cached, nil
+	}
+
+	if httpOptions.CacheDir != "" && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound) {
+		resp, err = WriteCachedResponse(httpOptions.CacheDir, rawURL, resp)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Error caching response for %s: %s.\n", rawURL, err)
+			if resp == nil {
+				return nil, err
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// fetchWithLimits sends a request through the rate limiter and retries it
+// while the host throttles it
+func fetchWithLimits(req *http.Request) (*http.Response, error) {
+	limiter := httpLimiter
+	host := strings.ToLower(req.URL.Host)
+	for attempt := 0; ; attempt++ {
+		limiter.wait(host)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			limiter.release()
+			limiter.record(0, err)
+			return nil, err
+		}
+
+		delay, throttled := GetRetryDelay(resp, attempt)
+		if !throttled || attempt >= maxFetchRetries {
+			limiter.record(resp.StatusCode, nil)
+			resp.Body = &limitedBody{ReadCloser: resp.Body, release: limiter.release}
+			return resp, nil
+		}
+
+		if attempt == 0 {
+			limiter.throttled()
+		}
+
+		resp.Body.Close()
+		limiter.release()
+		limiter.pause(host, delay)
+		fmt.Fprintf(os.Stderr, "[!] Requested %s. Attempt [%d/%d]. Response Code: %s. Waiting %s before retrying. \n", req.URL, attempt+1, maxFetchRetries, resp.Status, delay)
+	}
+}
+
+// cacheURLHeader stores the final URL of a cached response after redirects
+const cacheURLHeader = "X-Ptt-Cache-Url"
+
+// GetCachePath returns the path of the cached response of a URL
+//
+// Args:
+//
+//	cacheDir (string): The cache directory
+//	rawURL (string): The URL of the response
+//
+// Returns:
+//
+//	string: The path of the cache file
+func GetCachePath(cacheDir string, rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".http")
+}
+
+// ReadCachedResponse reads the cached response of a URL
+//
+// Args:
+//
+//	cacheDir (string): The cache directory
+//	rawURL (string): The URL of the response
+//
+// Returns:
+//
+//	*http.Response: The cached response or nil if the URL is not cached
+//	error: An error if one occurred
+func ReadCachedResponse(cacheDir string, rawURL string) (*http.Response, error) {
+	data, err := os.ReadFile(GetCachePath(cacheDir, rawURL))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the final URL so relative links resolve like the live response
+	if finalURL := resp.Header.Get(cacheURLHeader); finalURL != "" {
+		if parsed, err := url.Parse(finalURL); err == nil {
+			req.URL = parsed
+		}
+		resp.Header.Del(cacheURLHeader)
+	}
+
+	return resp, nil
+}
+
+// WriteCachedResponse reads the body of a response, saves the response to the
+// cache, and returns a copy of the response that can be read
+//
+// Args:
+//
+//	cacheDir (string): The cache directory
+//	rawURL (string): The URL of the response
+//	resp (*http.Response): The response to cache
+//
+// Returns:
+//
+//	*http.Response: The response with a readable body
+//	error: An error if one occurred
+func WriteCachedResponse(cacheDir string, rawURL string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.TransferEncoding = nil
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Transfer-Encoding")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set(cacheURLHeader, resp.Request.URL.String())
+
+	var buffer bytes.Buffer
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := resp.Write(&buffer); err != nil {
+		return nil, err
+	}
+	resp.Header.Del(cacheURLHeader)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Write to a unique temporary file first so readers never see a partial
+	// response and concurrent writers of the same URL do not collide
+	path := GetCachePath(cacheDir, rawURL)
+	temporary, err := os.CreateTemp(cacheDir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return resp, err
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err := temporary.Write(buffer.Bytes()); err != nil {
+		temporary.Close()
+		return resp, err
+	}
+	if err := temporary.Chmod(0o644); err != nil {
+		temporary.Close()
+		return resp, err
+	}
+	if err := temporary.Close(); err != nil {
+		return resp, err
+	}
+	if err := os.Rename(temporary.Name(), path); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// ProcessURL reads the contents of a URL and sends each sentence to the channel
+//
+// Args:
+//
+//	url (string): The URL to read
+//	ch (chan<- string): The channel to send the sentences to
+//	wg (*sync.WaitGroup): The WaitGroup to signal when done
+//	parsingMode (int): Change parsing mode for URL input. [0 = Strict,
+//	1 = Permissive, 2 = Maximum] [0-2].
+//	debugMode (int): A flag to print debug information
+//	extract (models.ExtractOptions): The HTML sources and JSON paths to
+//	extract from responses
+//
+// Returns:
+//
+//	None
+func ProcessURL(url string, ch chan<- string, wg *sync.WaitGroup, parsingMode int, debugMode int, extract models.ExtractOptions) {
+	defer wg.Done()
+
+	// Fetch the URL through the shared rate limiter
+	resp, err := FetchURL(url)
+	if err != nil {
+		if debugMode >= 2 {
+			fmt.Fprintf(os.Stderr, "[!] Error fetching URL %s.\n", url)
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	fmt.Fprintf(os.Stderr, "[+] Requested %s. Response Code: %s. Content-Type: %s. \n", url, resp.Status, resp.Header.Get("Content-Type"))
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		if debugMode >= 2 {
+			fmt.Fprintf(os.Stderr, "[!] Error fetching URL service returned %s. Removing target. %s\n", resp.Status, url)
+		}
+		return
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if debugMode >= 1 {
+			fmt.Fprintf(os.Stderr, "[!] Error reading response body from URL %s.\n", url)
+		}
+		return
+	}
+
+	// Check the Content-Type of the response
+	contentType := resp.Header.Get("Content-Type")
+	lines, values, err := ExtractResponseLines(contentType, body, extract)
+	if err != nil {
+		if debugMode >= 1 {
+			fmt.Fprintf(os.Stderr, "[!] Error parsing response from URL %s.\n", url)
+		}
+		return
+	}
+
+	if debugMode == 1 {
+		fmt.Fprintf(os.Stderr, "[?] URL: %s\n", url)
+		fmt.Fprintf(os.Stderr, "[?] Content-Type: %s\n", contentType)
+		fmt.Fprintf(os.Stderr, "[?] Parsing Mode: %d\n", parsingMode)
+	} else if debugMode == 2 {
+		fmt.Fprintf(os.Stderr, "[?] URL: %s\n", url)
+		fmt.Fprintf(os.Stderr, "[?] Content-Type: %s\n", contentType)
+		fmt.Fprintf(os.Stderr, "[?] Parsing Mode: %d\n", parsingMode)
+		fmt.Fprintf(os.Stderr, "[?] Line Count: %d\n", len(lines))
+		fmt.Fprintf(os.Stderr, "[?] Sample Lines:\n")
+		for i := 0; i < 5 && i < len(lines); i++ {
+			fmt.Fprintf(os.Stderr, "%s\n", lines[i])
+		}
+	}
+
+	ParseLinesToChannel(lines, IsExtractedContentType(contentType), parsingMode, extract.Punctuation, ch)
+	for _, value := range values {
+		ch <- value
+	}
+}
+
+// ExtractResponseLines extracts the lines of text from a response body. HTML
+// responses are parsed for the text of the selected sources, JSON responses
+// are parsed for the string values of the selected paths, and other responses
+// are unescaped and split into lines.
+//
+// Args:
+//
+//	contentType (string): The Content-Type of the response
+//	body ([]byte): The body of the response
+//	extract (models.ExtractOptions): The HTML sources and JSON paths to
+//	extract from responses
+//
+// Returns:
+//
+//	[]string: The lines of text in the response
+//	[]string: The email and username values to keep whole
+//	error: An error if one occurred
+func ExtractResponseLines(contentType string, body []byte, extract models.ExtractOptions) ([]string, []string, error) {
+	if strings.Contains(contentType, "text/html") {
+		return document.ExtractHTMLSources(string(body), extract.HTMLSources)
+	}
+
+	// Responses that are not valid JSON are read as text
+	if strings.Contains(contentType, "json") {
+		if lines, err := document.ExtractJSONText(body, extract.JSONPaths); err == nil {
+			return lines, nil, nil
+		}
+	}
+
+	return strings.Split(html.UnescapeString(string(body)), "\n"), nil, nil
+}
+
+// IsExtractedContentType checks if a response of the content type is parsed
+// into extracted text like a document rather than read as raw text lines
+//
+// Args:
+//
+//	contentType (string): The Content-Type of the response
+//
+// Returns:
+//
+//	bool: True if the response is HTML or JSON
+func IsExtractedContentType(contentType string) bool {
+	return strings.Contains(contentType, "text/html") || strings.Contains(contentType, "json")
+}
+
+// ParseLinesToChannel splits lines into sentences and phrases based on the
+// parsing mode and sends them to the channel. Lines extracted from documents
+// are skipped if they contain characters outside of the parsing character set
+// and raw text lines are kept only if they do. Sentences, phrases, and words
+// are found with Unicode-aware boundaries so text in any script is parsed.
+//
+// Args:
+//
+//	lines ([]string): The lines to parse
+//	extracted (bool): If the lines were extracted from an HTML or other
+//	document rather than read as raw text
+//	parsingMode (int): Change parsing mode for input. [0 = Strict,
+//	1 = Permissive, 2 = Maximum] [0-2].
+//	punctuation (string): The characters that split sentences into phrases.
+//	DefaultPunctuation is used if empty.
+//	ch (chan<- string): The channel to send the sentences to
+//
+// Returns:
+//
+//	None
+func ParseLinesToChannel(lines []string, extracted bool, parsingMode int, punctuation string, ch chan<- string) {
+	if punctuation == "" {
+		punctuation = DefaultPunctuation
 	}
 
 	// Iterate over the lines and split them
 	for _, line := range lines {
-		if parsingMode == 0 {
-			textMatch, _ := regexp.MatchString(`[^a-zA-Z0-9.,;:!? ]`, line)
-			if strings.Contains(contentType, "text/html") {
-				if textMatch {
-					continue
+		if IsParsableText(line, parsingMode, punctuation) != extracted {
+			continue
+		}
+
+		for _, sentence := range SplitSentences(line) {
+
+			if parsingMode >= 1 {
+				if phrases := SplitPhrases(sentence, punctuation); len(phrases) > 1 {
+					for _, phrase := range phrases {
+						ch <- phrase
+					}
 				}
-			} else {
-				if !textMatch {
-					continue
+			}
+
+			if parsingMode >= 2 {
+				for _, word := range SplitWords(sentence) {
+					ch <- word
+				}
+
+				var allNGrams []string
+				for n := 2; n <= 7; n++ {
+					allNGrams = append(allNGrams, GenerateNGrams(sentence, n)...)
+				}
+				for _, nGram := range allNGrams {
+					if nGram != "" {
+						nGram = strings.TrimSpace(nGram)
+						ch <- nGram
+
+						noDot := strings.TrimRight(nGram, ".")
+						ch <- noDot
+
+						noComma := strings.TrimRight(nGram, ",")
+						ch <- noComma
+
+						frontParse := strings.TrimLeft(nGram, ", ")
+						ch <- frontParse
+
+						noSpace := strings.ReplaceAll(nGram, " ", "")
+						ch <- noSpace
+
+					}
 				}
 			}
-		} else if parsingMode >= 1 {
-			textMatch, _ := regexp.MatchString(`[^a-zA-Z0-9.,;:!?'"\- \/+_#@"\[\]]`, line)
-			if strings.Contains(contentType, "text/html") {
-				if textMatch {
-					continue
+
+			ch <- sentence
+		}
+	}
+}
+
+// ReadJSONToArray reads the contents of a transformation template file and
+// returns a slice of template structs.
+//
+// Args:
+//
+//	fs (FileSystem): The filesystem to read the file from (used for testing)
+//	fileArray ([]string): The name of the files to read
+//
+// Returns:
+//
+//	templates ([]models.TemplateFileOperation): The slice of template structs
+func ReadJSONToArray(fs models.FileSystem, filenames []string) []models.TemplateFileOperation {
+	var combinedTemplate []models.TemplateFileOperation
+	var template []models.TemplateFileOperation
+
+	i := 0
+	for i < len(filenames) {
+		filename := filenames[i]
+		// Check to see if a directory was passed
+		// If so, read all files in the directory and append them to the filenames
+		// slice
+		if IsFileSystemDirectory(filename) {
+			err := filepath.Walk(filename, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
+					filenames = append(filenames, path)
 				}
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error walking the path %v: %v.\n", filename, err)
+				os.Exit(1)
+			}
+		} else {
+			data, err := fs.ReadFile(filename)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error reading file %s.\n", filename)
+				os.Exit(1)
+			}
+
+			err = json.Unmarshal(data, &template)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!] Error unmarshalling JSON file %s.\n", filename)
+				os.Exit(1)
+			}
+
+			combinedTemplate = append(combinedTemplate, template...)
+		}
+		i++
+	}
+
+	alphaRe := regexp.MustCompile(`[a-zA-Z]`)
+	numRe := regexp.MustCompile(`[0-9]`)
+
+	for _, template := range combinedTemplate {
+		if !numRe.MatchString(fmt.Sprintf("%v", template.StartIndex)) || !numRe.MatchString(fmt.Sprintf("%v", template.EndIndex)) {
+			fmt.Fprintf(os.Stderr, "[!] Error: StartIndex and EndIndex must be integers.\n")
+			os.Exit(1)
+		}
+
+		if !alphaRe.MatchString(fmt.Sprintf("%v", template.Verbose)) {
+			fmt.Fprintf(os.Stderr, "[!] Error: Verbose must be a boolean.\n")
+			os.Exit(1)
+		}
+
+		if !alphaRe.MatchString(fmt.Sprintf("%v", template.ReplacementMask)) {
+			fmt.Fprintf(os.Stderr, "[!] Error: ReplacementMask must be a string.\n")
+			os.Exit(1)
+		}
+
+		if !alphaRe.MatchString(fmt.Sprintf("%v", template.Bypass)) {
+			fmt.Fprintf(os.Stderr, "[!] Error: Bypass must be a boolean.\n")
+			os.Exit(1)
+		}
+
+		if !alphaRe.MatchString(fmt.Sprintf("%v", template.TransformationMode)) {
+			fmt.Fprintf(os.Stderr, "[!] Error: TransformationMode must be a string.\n")
+			os.Exit(1)
+		}
+
+		if !numRe.MatchString(fmt.Sprintf("%v", template.WordRangeStart)) || !numRe.MatchString(fmt.Sprintf("%v", template.WordRangeEnd)) {
+			fmt.Fprintf(os.Stderr, "[!] Error: WordRangeStart and WordRangeEnd must be integers.\n")
+			os.Exit(1)
+		}
+	}
+
+	return combinedTemplate
+}
+
+// ProcessURLFile reads the contents of a file containing URLs and sends each
+// URL to the channel
+//
+// Args:
+// filePath (string): The path to the file containing URLs
+// ch (chan<- string): The channel to send the URLs to
+// wg (*sync.WaitGroup): The WaitGroup to signal when done
+// parsingMode (int): Change parsing mode for URL input. [0 = Strict,
+// 1 = Permissive, 2 = Maximum] [0-2].
+// debugMode (int): A flag to print debug information
+// extract (models.ExtractOptions): The HTML sources and JSON paths to extract
+//
+// Returns:
+// None
+func ProcessURLFile(filePath string, ch chan<- string, wg *sync.WaitGroup, parsingMode int, debugMode int, extract models.ExtractOptions) {
+	defer wg.Done()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Error opening file %v: %v.\n", filePath, err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if IsValidURL(line) {
+			wg.Add(1)
+			go ProcessURL(line, ch, wg, parsingMode, debugMode, extract)
+		} else {
+			fmt.Fprintf(os.Stderr, "[!] Rejected URL: %s.\n", line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Error reading file %v: %v.\n", filePath, err)
+	}
+}
+
+// CollectURLs reads the URLs from the given URLs, files, directories, and
+// archives of URL lists without fetching them
+//
+// Args:
+//
+//	urls ([]string): The URLs or files of URLs to read
+//	include ([]string): Glob patterns of directory and archive members to read
+//	exclude ([]string): Glob patterns of directory and archive members to skip
+//
+// Returns:
+//
+//	[]string: The valid URLs
+//	error: An error if one occurred
+func CollectURLs(urls []string, include []string, exclude []string) ([]string, error) {
+	var collected []string
+
+	readURLLines := func(name string, reader io.Reader) error {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if IsValidURL(line) {
+				collected = append(collected, line)
 			} else {
-				if !textMatch {
+				fmt.Fprintf(os.Stderr, "[!] Rejected URL: %s.\n", line)
+			}
+		}
+		return scanner.Err()
+	}
+
+	readURLFile := func(filename string) error {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return readURLLines(filename, file)
+	}
+
+	for _, iURL := range urls {
+		if IsValidURL(iURL) {
+			collected = append(collected, iURL)
+		} else if IsFileSystemDirectory(iURL) {
+			files, err := GetFilesInDirectory(iURL)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if !MatchArchiveGlobs(file, include, exclude) {
 					continue
 				}
+				if err := readURLFile(file); err != nil {
+					return nil, err
+				}
+			}
+		} else if IsArchiveFile(iURL) {
+			if err := WalkArchive(&models.RealFileSystem{}, iURL, include, exclude, readURLLines); err != nil {
+				return nil, err
+			}
+		} else if IsValidFile(iURL) {
+			if err := readURLFile(iURL); err != nil {
+				return nil, err
 			}
+		} else {
+			fmt.Fprintf(os.Stderr, "[!] Rejected URL or file: %s.\n", iURL)
+			return nil, fmt.Errorf("invalid input: %s", iURL)
 		}
+	}
 
-		sentences := strings.Split(line, ".")
-		for _, sentence := range sentences {
+	return collected, nil
+}
 
-			if parsingMode >= 1 {
-				phrases := strings.Split(sentence, ",")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+// CrawlURLs fetches the seed URLs and follows links on the same hosts up to
+// the crawl depth and sends each sentence to the channel. Pages are fetched
+// one at a time and robots.txt rules are followed unless ignored. Each page
+// is visited once and its sentences are also counted for the seed it was
+// reached from if a sources map is given.
+//
+// Args:
+//
+//	seeds ([]string): The URLs to start crawling from
+//	parsingMode (int): Change parsing mode for URL input. [0 = Strict,
+//	1 = Permissive, 2 = Maximum] [0-2].
+//	debugMode (int): A flag to print debug information
+//	options (models.CrawlOptions): The depth, scope, and limits of the crawl
+//	extract (models.ExtractOptions): The HTML sources and JSON paths to
+//	extract from responses
+//	ch (chan<- string): The channel to send the sentences to
+//	sources (map[string]map[string]int): A map of seed URLs to fill with the
+//	sentences from each seed, or nil to not track the source of sentences
+//
+// Returns:
+//
+//	error: An error if one occurred
+func CrawlURLs(seeds []string, parsingMode int, debugMode int, options models.CrawlOptions, extract models.ExtractOptions, ch chan<- string, sources map[string]map[string]int) error {
+	include, err := compileCrawlPatterns(options.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := compileCrawlPatterns(options.Exclude)
+	if err != nil {
+		return err
+	}
 
-				phrases = strings.Split(sentence, ";")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+	type crawlTarget struct {
+		url   string
+		depth int
+		seed  string
+	}
 
-				phrases = strings.Split(sentence, ":")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+	var queue []crawlTarget
+	visited := make(map[string]bool)
+	scope := make(map[string]bool)
+	robots := make(map[string]models.RobotsRules)
 
-				phrases = strings.Split(sentence, "!")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+	enqueue := func(link string, depth int, seed string, isSeed bool) {
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return
+		}
+		parsed.Fragment = ""
+		parsed.RawFragment = ""
+		link = parsed.String()
 
-				phrases = strings.Split(sentence, "?")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+		if visited[link] || !scope[strings.ToLower(parsed.Host)] {
+			return
+		}
+		if !isSeed && !MatchCrawlPatterns(link, include, exclude) {
+			return
+		}
+
+		visited[link] = true
+		queue = append(queue, crawlTarget{link, depth, seed})
+	}
+
+	getRobots := func(target *url.URL) models.RobotsRules {
+		host := strings.ToLower(target.Host)
+		if rules, ok := robots[host]; ok {
+			return rules
+		}
+
+		var rules models.RobotsRules
+		resp, err := FetchURL(target.Scheme + "://" + target.Host + "/robots.txt")
+		if err == nil {
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr == nil && resp.StatusCode == http.StatusOK {
+				rules = ParseRobotsTxt(string(body), "ptt")
+			}
+		}
+
+		robots[host] = rules
+		return rules
+	}
+
+	for _, seed := range seeds {
+		parsed, err := url.Parse(seed)
+		if err != nil {
+			continue
+		}
+		scope[strings.ToLower(parsed.Host)] = true
+		enqueue(seed, 0, seed, true)
+
+		if options.Sitemap {
+			for _, page := range discoverSitemapURLs(parsed, getRobots(parsed).Sitemaps, debugMode) {
+				enqueue(page, 0, seed, false)
+			}
+		}
+	}
+
+	pages := 0
+	for len(queue) > 0 {
+		if options.MaxPages > 0 && pages >= options.MaxPages {
+			if debugMode >= 1 {
+				fmt.Fprintf(os.Stderr, "[!] Crawl page limit of %d reached. Skipping %d queued URLs.\n", options.MaxPages, len(queue))
+			}
+			break
+		}
+
+		target := queue[0]
+		queue = queue[1:]
+
+		parsed, _ := url.Parse(target.url)
+		if !options.IgnoreRobots && !IsAllowedByRobots(getRobots(parsed), parsed.RequestURI()) {
+			if debugMode >= 1 {
+				fmt.Fprintf(os.Stderr, "[!] Skipping %s disallowed by robots.txt.\n", target.url)
+			}
+			continue
+		}
+
+		resp, err := FetchURL(target.url)
+		if err != nil {
+			if debugMode >= 1 {
+				fmt.Fprintf(os.Stderr, "[!] Error fetching URL %s.\n", target.url)
 			}
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		pages++
+
+		contentType := resp.Header.Get("Content-Type")
+		fmt.Fprintf(os.Stderr, "[+] Crawled %s. Depth [%d/%d]. Response Code: %s. Content-Type: %s. \n", target.url, target.depth, options.Depth, resp.Status, contentType)
+
+		// Skip failed requests, redirects off the site, and binary content
+		if err != nil || (resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound) {
+			continue
+		}
+		if !scope[strings.ToLower(resp.Request.URL.Host)] {
+			continue
+		}
+		if contentType != "" && !strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, "json") {
+			continue
+		}
+
+		lines, values, err := ExtractResponseLines(contentType, body, extract)
+		if err != nil {
+			continue
+		}
+
+		if debugMode >= 1 {
+			fmt.Fprintf(os.Stderr, "[?] URL: %s\n", target.url)
+			fmt.Fprintf(os.Stderr, "[?] Line Count: %d\n", len(lines))
+		}
 
-			if parsingMode >= 2 {
-				phrases := strings.Split(sentence, " ")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
+		if sources == nil {
+			ParseLinesToChannel(lines, IsExtractedContentType(contentType), parsingMode, extract.Punctuation, ch)
+			for _, value := range values {
+				ch <- value
+			}
+		} else {
+			// Count the sentences of the page for its seed as they are sent
+			pageCh := make(chan string)
+			go func() {
+				ParseLinesToChannel(lines, IsExtractedContentType(contentType), parsingMode, extract.Punctuation, pageCh)
+				for _, value := range values {
+					pageCh <- value
 				}
+				close(pageCh)
+			}()
 
-				phrases = strings.Split(sentence, "-")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+			if sources[target.seed] == nil {
+				sources[target.seed] = make(map[string]int)
+			}
+			for sentence := range pageCh {
+				sources[target.seed][sentence]++
+				ch <- sentence
+			}
+		}
 
-				phrases = strings.Split(sentence, "'")
-				for _, phrase := range phrases {
-					if phrase != "" {
-						phrase = strings.TrimSpace(phrase)
-						ch <- phrase
-					}
-				}
+		if target.depth < options.Depth && strings.Contains(contentType, "text/html") {
+			for _, link := range document.ExtractHTMLLinks(string(body), resp.Request.URL) {
+				enqueue(link, target.depth+1, target.seed, false)
+			}
+		}
+	}
 
-				twoGrams := GenerateNGrams(sentence, 2)
-				threeGrams := GenerateNGrams(sentence, 3)
-				fourGrams := GenerateNGrams(sentence, 4)
-				fiveGrams := GenerateNGrams(sentence, 5)
-				sixGrams := GenerateNGrams(sentence, 6)
-				sevenGrams := GenerateNGrams(sentence, 7)
-				allNGrams := append(twoGrams, append(threeGrams, append(fourGrams, append(fiveGrams, append(sixGrams, sevenGrams...)...)...)...)...)
-				for _, nGram := range allNGrams {
-					if nGram != "" {
-						nGram = strings.TrimSpace(nGram)
-						ch <- nGram
+	return nil
+}
 
-						noDot := strings.TrimRight(nGram, ".")
-						ch <- noDot
+// discoverSitemapURLs fetches the sitemaps of a site and returns the page
+// URLs they list. The default /sitemap.xml is checked along with the sitemaps
+// listed in robots.txt and nested sitemap indexes are followed.
+func discoverSitemapURLs(site *url.URL, sitemaps []string, debugMode int) []string {
+	const maxSitemaps = 50
+	var pages []string
 
-						noComma := strings.TrimRight(nGram, ",")
-						ch <- noComma
+	queue := append([]string{site.Scheme + "://" + site.Host + "/sitemap.xml"}, sitemaps...)
+	seen := make(map[string]bool)
 
-						frontParse := strings.TrimLeft(nGram, ", ")
-						ch <- frontParse
+	for len(queue) > 0 && len(seen) < maxSitemaps {
+		sitemap := queue[0]
+		queue = queue[1:]
+		if seen[sitemap] {
+			continue
+		}
+		seen[sitemap] = true
 
-						noSpace := strings.ReplaceAll(nGram, " ", "")
-						ch <- noSpace
+		resp, err := FetchURL(sitemap)
+		if err != nil {
+			continue
+		}
+		reader, err := GetDecompressedReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			continue
+		}
+		body, err := io.ReadAll(reader)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
 
-					}
-				}
-			}
+		locations, nested := document.ExtractSitemapLocations(body)
+		if debugMode >= 1 {
+			fmt.Fprintf(os.Stderr, "[?] Sitemap: %s\n", sitemap)
+			fmt.Fprintf(os.Stderr, "[?] URL Count: %d\n", len(locations))
+		}
+		pages = append(pages, locations...)
+		queue = append(queue, nested...)
+	}
 
-			if sentence != "" {
-				sentence = strings.TrimSpace(sentence)
-				ch <- sentence
-			}
+	return pages
+}
+
+// compileCrawlPatterns compiles the regular expressions used to scope a crawl
+func compileCrawlPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid crawl pattern %s: %s", pattern, err)
 		}
+		compiled = append(compiled, re)
 	}
+	return compiled, nil
 }
 
-// ReadJSONToArray reads the contents of a transformation template file and
-// returns a slice of template structs.
+// MatchCrawlPatterns checks if a URL should be crawled. The URL must match one
+// of the include patterns if any are given and none of the exclude patterns.
 //
 // Args:
 //
-//	fs (FileSystem): The filesystem to read the file from (used for testing)
-//	fileArray ([]string): The name of the files to read
+//	link (string): The URL to check
+//	include ([]*regexp.Regexp): The patterns of URLs to crawl
+//	exclude ([]*regexp.Regexp): The patterns of URLs to skip
 //
 // Returns:
 //
-//	templates ([]models.TemplateFileOperation): The slice of template structs
-func ReadJSONToArray(fs models.FileSystem, filenames []string) []models.TemplateFileOperation {
-	var combinedTemplate []models.TemplateFileOperation
-	var template []models.TemplateFileOperation
-
-	i := 0
-	for i < len(filenames) {
-		filename := filenames[i]
-		// Check to see if a directory was passed
-		// If so, read all files in the directory and append them to the filenames
-		// slice
-		if IsFileSystemDirectory(filename) {
-			err := filepath.Walk(filename, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
-					filenames = append(filenames, path)
-				}
-				return nil
-			})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error walking the path %v: %v.\n", filename, err)
-				os.Exit(1)
-			}
-		} else {
-			data, err := fs.ReadFile(filename)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error reading file %s.\n", filename)
-				os.Exit(1)
-			}
-
-			err = json.Unmarshal(data, &template)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[!] Error unmarshalling JSON file %s.\n", filename)
-				os.Exit(1)
-			}
-
-			combinedTemplate = append(combinedTemplate, template...)
+//	bool: True if the URL should be crawled
+func MatchCrawlPatterns(link string, include []*regexp.Regexp, exclude []*regexp.Regexp) bool {
+	for _, re := range exclude {
+		if re.MatchString(link) {
+			return false
 		}
-		i++
 	}
 
-	alphaRe := regexp.MustCompile(`[a-zA-Z]`)
-	numRe := regexp.MustCompile(`[0-9]`)
+	if len(include) == 0 {
+		return true
+	}
 
-	for _, template := range combinedTemplate {
-		if !numRe.MatchString(fmt.Sprintf("%v", template.StartIndex)) || !numRe.MatchString(fmt.Sprintf("%v", template.EndIndex)) {
-			fmt.Fprintf(os.Stderr, "[!] Error: StartIndex and EndIndex must be integers.\n")
-			os.Exit(1)
+	for _, re := range include {
+		if re.MatchString(link) {
+			return true
 		}
+	}
 
-		if !alphaRe.MatchString(fmt.Sprintf("%v", template.Verbose)) {
-			fmt.Fprintf(os.Stderr, "[!] Error: Verbose must be a boolean.\n")
-			os.Exit(1)
-		}
+	return false
+}
 
-		if !alphaRe.MatchString(fmt.Sprintf("%v", template.ReplacementMask)) {
-			fmt.Fprintf(os.Stderr, "[!] Error: ReplacementMask must be a string.\n")
-			os.Exit(1)
-		}
+// ParseRobotsTxt parses a robots.txt file and returns the rules for the user
+// agent. The rules of a group naming the user agent are used before the rules
+// of the wildcard group.
+//
+// Args:
+//
+//	text (string): The contents of the robots.txt file
+//	userAgent (string): The name of the crawler
+//
+// Returns:
+//
+//	models.RobotsRules: The rules that apply to the crawler
+func ParseRobotsTxt(text string, userAgent string) models.RobotsRules {
+	var specific, wildcard, rules models.RobotsRules
+	var agents []string
+	matched := false
+	inRules := false
+	userAgent = strings.ToLower(userAgent)
 
-		if !alphaRe.MatchString(fmt.Sprintf("%v", template.Bypass)) {
-			fmt.Fprintf(os.Stderr, "[!] Error: Bypass must be a boolean.\n")
-			os.Exit(1)
+	for _, line := range strings.Split(text, "\n") {
+		if index := strings.Index(line, "#"); index != -1 {
+			line = line[:index]
 		}
 
-		if !alphaRe.MatchString(fmt.Sprintf("%v", template.TransformationMode)) {
-			fmt.Fprintf(os.Stderr, "[!] Error: TransformationMode must be a string.\n")
-			os.Exit(1)
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
 		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
 
-		if !numRe.MatchString(fmt.Sprintf("%v", template.WordRangeStart)) || !numRe.MatchString(fmt.Sprintf("%v", template.WordRangeEnd)) {
-			fmt.Fprintf(os.Stderr, "[!] Error: WordRangeStart and WordRangeEnd must be integers.\n")
-			os.Exit(1)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+			if value != "*" && strings.Contains(userAgent, strings.ToLower(value)) {
+				matched = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			for _, agent := range agents {
+				target := &wildcard
+				if agent != "*" {
+					if !strings.Contains(userAgent, agent) {
+						continue
+					}
+					target = &specific
+				}
+				if key == "allow" {
+					target.Allow = append(target.Allow, value)
+				} else {
+					target.Disallow = append(target.Disallow, value)
+				}
+			}
+		case "sitemap":
+			rules.Sitemaps = append(rules.Sitemaps, value)
 		}
 	}
 
-	return combinedTemplate
+	if matched {
+		rules.Allow, rules.Disallow = specific.Allow, specific.Disallow
+	} else {
+		rules.Allow, rules.Disallow = wildcard.Allow, wildcard.Disallow
+	}
+
+	return rules
 }
 
-// ProcessURLFile reads the contents of a file containing URLs and sends each
-// URL to the channel
+// IsAllowedByRobots checks if a path may be crawled under the robots.txt
+// rules. The longest matching rule wins and allow rules win ties. The * and $
+// wildcards are supported.
 //
 // Args:
-// filePath (string): The path to the file containing URLs
-// ch (chan<- string): The channel to send the URLs to
-// wg (*sync.WaitGroup): The WaitGroup to signal when done
-// parsingMode (int): Change parsing mode for URL input. [0 = Strict,
-// 1 = Permissive, 2 = Maximum] [0-2].
-// debugMode (int): A flag to print debug information
+//
+//	rules (models.RobotsRules): The rules that apply to the crawler
+//	path (string): The path and query of the URL
 //
 // Returns:
-// None
-func ProcessURLFile(filePath string, ch chan<- string, wg *sync.WaitGroup, parsingMode int, debugMode int) {
-	defer wg.Done()
-	sleepOnStart := false
+//
+//	bool: True if the path may be crawled
+func IsAllowedByRobots(rules models.RobotsRules, path string) bool {
+	allowLength, disallowLength := -1, -1
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "[!] Error opening file %v: %v.\n", filePath, err)
-		return
+	for _, pattern := range rules.Allow {
+		if len(pattern) > allowLength && matchRobotsPattern(pattern, path) {
+			allowLength = len(pattern)
+		}
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if IsValidURL(line) {
-			wg.Add(1)
-			go ProcessURL(line, ch, wg, parsingMode, debugMode, sleepOnStart)
-		} else {
-			fmt.Fprintf(os.Stderr, "[!] Rejected URL: %s.\n", line)
+	for _, pattern := range rules.Disallow {
+		if len(pattern) > disallowLength && matchRobotsPattern(pattern, path) {
+			disallowLength = len(pattern)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "[!] Error reading file %v: %v.\n", filePath, err)
+	return allowLength >= disallowLength
+}
+
+// matchRobotsPattern checks if a path matches a robots.txt path pattern
+func matchRobotsPattern(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expression += "$"
 	}
+
+	matched, err := regexp.MatchString(expression, path)
+	return err == nil && matched
 }
 
 // ----------------------------------------------------------------------------
@@ -943,7 +3706,7 @@ func SubstringMap(sMap map[string]int, sIndex int, eIndex int, bypass bool, debu
 		maxLen := eIndex
 		if sIndex > len(s) {
 			if debug {
-				fmt.Fprintf(os.Stderr, fmt.Sprintf("[!] Error: Start index is out of bounds: %s.\n", s))
+				fmt.Fprintf(os.Stderr, "[!] Error: Start index is out of bounds: %s.\n", s)
 			}
 			continue
 		} else if eIndex > len(s) {
@@ -959,6 +3722,162 @@ func SubstringMap(sMap map[string]int, sIndex int, eIndex int, bypass bool, debu
 	return newMap
 }
 
+// DefaultPunctuation is the set of characters that split sentences into
+// phrases in the permissive and maximum parsing modes. The ASCII marks are
+// followed by their full-width, CJK, and Arabic equivalents.
+const DefaultPunctuation = ",;:!?，、；：！？،؛"
+
+// permissivePunctuation are the extra ASCII characters allowed in lines by the
+// permissive and maximum parsing modes
+const permissivePunctuation = `'"-/+_#@[]`
+
+// passphrasePunctuation are the full stops, commas, and semicolons removed
+// from text before it is turned into passphrases
+const passphrasePunctuation = ".,;。，、；．｡､"
+
+// IsParsableText checks if every character of a line can be parsed into
+// sentences for a parsing mode. Letters, combining marks, and digits of any
+// script are allowed along with spaces, sentence terminators, and the phrase
+// punctuation. The permissive and maximum modes also allow dashes, quotes,
+// brackets, and common symbols.
+//
+// Args:
+//
+//	line (string): The line to check
+//	parsingMode (int): The parsing mode [0 = Strict, 1 = Permissive,
+//	2 = Maximum]
+//	punctuation (string): The characters that split phrases
+//
+// Returns:
+//
+//	bool: True if the line only contains allowed characters
+func IsParsableText(line string, parsingMode int, punctuation string) bool {
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || unicode.IsSpace(r):
+		case unicode.Is(unicode.Sentence_Terminal, r):
+		case strings.ContainsRune(DefaultPunctuation, r) || strings.ContainsRune(punctuation, r):
+		case parsingMode >= 1 && strings.ContainsRune(permissivePunctuation, r):
+		case parsingMode >= 1 && unicode.In(r, unicode.Pd, unicode.Pi, unicode.Pf):
+		case parsingMode >= 1 && r > unicode.MaxASCII && unicode.In(r, unicode.Ps, unicode.Pe):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// SplitSentences splits text into sentences at sentence terminators of any
+// script such as ".", "!", "?", "。", and "।". Terminators only end a sentence
+// before whitespace or the end of the text, so abbreviations, decimals, and
+// domains stay whole, except for non-ASCII terminators which are used in
+// scripts without spaces. Terminators and closing quotes or brackets after
+// them are removed.
+//
+// Args:
+//
+//	text (string): The text to split
+//
+// Returns:
+//
+//	[]string: The trimmed sentences without empty sentences
+func SplitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		if !unicode.Is(unicode.Sentence_Terminal, runes[i]) {
+			continue
+		}
+
+		// Consume repeated terminators and closing punctuation
+		end := i
+		wide := false
+		for i < len(runes) && (unicode.Is(unicode.Sentence_Terminal, runes[i]) || unicode.In(runes[i], unicode.Pe, unicode.Pf) || runes[i] == '"' || runes[i] == '\'') {
+			wide = wide || (runes[i] > unicode.MaxASCII && unicode.Is(unicode.Sentence_Terminal, runes[i]))
+			i++
+		}
+
+		if i < len(runes) && !unicode.IsSpace(runes[i]) && !wide {
+			i--
+			continue
+		}
+
+		if sentence := strings.TrimSpace(string(runes[start:end])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = i
+		i--
+	}
+
+	if start < len(runes) {
+		if sentence := strings.TrimSpace(string(runes[start:])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+
+	return sentences
+}
+
+// SplitPhrases splits a sentence into phrases at any character in the
+// punctuation set
+//
+// Args:
+//
+//	sentence (string): The sentence to split
+//	punctuation (string): The characters that split phrases
+//
+// Returns:
+//
+//	[]string: The trimmed phrases without empty phrases
+func SplitPhrases(sentence string, punctuation string) []string {
+	var phrases []string
+	for _, phrase := range strings.FieldsFunc(sentence, func(r rune) bool {
+		return strings.ContainsRune(punctuation, r)
+	}) {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	return phrases
+}
+
+// SplitWords splits a sentence into words at whitespace of any script and
+// trims the punctuation around each word. Words joined by dashes or
+// apostrophes are followed by their parts.
+//
+// Args:
+//
+//	sentence (string): The sentence to split
+//
+// Returns:
+//
+//	[]string: The words and the parts of compound words
+func SplitWords(sentence string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(sentence, unicode.IsSpace) {
+		word = strings.TrimFunc(word, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '#' && r != '@'
+		})
+		if word == "" {
+			continue
+		}
+		words = append(words, word)
+
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return unicode.Is(unicode.Pd, r) || r == '\'' || r == '’'
+		})
+		if len(parts) > 1 {
+			words = append(words, parts...)
+		}
+	}
+
+	return words
+}
+
 // GenerateNGrams generates n-grams from a string of text
 // and returns a slice of n-grams
 //
@@ -990,9 +3909,12 @@ func GenerateNGrams(text string, n int) []string {
 // Returns:
 // []string: A slice of passphrases
 func GeneratePassphrase(text string, n int) []string {
-	text = strings.ReplaceAll(text, ".", "")
-	text = strings.ReplaceAll(text, ",", "")
-	text = strings.ReplaceAll(text, ";", "")
+	text = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(passphrasePunctuation, r) {
+			return -1
+		}
+		return r
+	}, text)
 	words := strings.Fields(text)
 	var passphrases []string
 
@@ -1059,6 +3981,9 @@ func CheckASCIIString(str string) bool {
 	return true
 }
 
+// hexStringPattern matches plaintext in the $HEX[...] format
+var hexStringPattern = regexp.MustCompile(`^\$HEX\[[a-zA-Z0-9]*\]$`)
+
 // CheckHexString is used to identify plaintext in the $HEX[...] format
 //
 // Args:
@@ -1069,11 +3994,29 @@ func CheckASCIIString(str string) bool {
 //
 //	(bool): Returns true if it matches and false if it did not
 func CheckHexString(s string) bool {
-	var validateInput = regexp.MustCompile(`^\$HEX\[[a-zA-Z0-9]*\]$`).MatchString
-	if validateInput(s) == false {
-		return false
+	return hexStringPattern.MatchString(s)
+}
+
+// DecodeHexString decodes plaintext in the $HEX[...] format. Other strings and
+// hex that is not valid are returned unchanged.
+//
+// Args:
+//
+//	s (string): The string to decode
+//
+// Returns:
+//
+//	string: The decoded string
+func DecodeHexString(s string) string {
+	if !CheckHexString(s) {
+		return s
 	}
-	return true
+
+	decoded, err := hex.DecodeString(s[5 : len(s)-1])
+	if err != nil {
+		return s
+	}
+	return string(decoded)
 }
 
 // CheckAreMapsEqual checks if two maps are equal by comparing the length of the maps
@@ -1,9 +1,31 @@
 package utils
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jakewnuk/ptt/pkg/models"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 // ----------------------------------------------------------------------------
@@ -11,8 +33,46 @@ import (
 // ----------------------------------------------------------------------------
 // ** Loading and Processing Functions **
 // - ReadFilesToMap()
+// - ReadFileToMap()
+// - IsJSONInput()
+// - ReadJSONToMap()
+// - IsCSVRecordInput()
+// - ReadCSVRecordsToMap()
+// - ReadLinesToMap()
+// - GetSkippedLineCount()
+// - SplitColumnSelector()
+// - SplitSourceWeight()
+// - ReadColumnToMap()
+// - GetDecompressedReader()
+// - MatchArchiveGlobs()
+// - WalkArchive()
+// - ReadDocumentsToMap()
+// - CrawlURLs()
+// - ReadURLsToMap()
+// - ParseLinesToChannel()
+// - ParseRobotsTxt()
+// - IsAllowedByRobots()
+// - ParseHTTPHeader()
+// - LoadCookieJar()
+// - ConfigureHTTPClient()
+// - ParseRetryAfter()
+// - FetchURL()
+// - GetURLStats()
+// - ReadCachedResponse()
+// - GetDecodedReader()
+// - DetectEncoding()
 // - LoadStdinToMap()
+// - ParsePotfileLine()
+// - ParsePotfileMap()
+// - ParseCredentialLine()
+// - JoinCredentialsWithPotfile()
+// - CredentialsToMap()
 // - CombineMaps()
+// - GetNormalizedTotal()
+// - NormalizeMap()
+// - GetWeightScale()
+// - WeightMap()
+// - AddProvenance()
 // - ReadJSONToArray()
 //
 // ** Transformation Functions **
@@ -24,12 +84,17 @@ import (
 // - ReplaceSubstring()
 // - ReplaceAllSubstring()
 // - SubstringMap()
+// - IsParsableText()
+// - SplitSentences()
+// - SplitPhrases()
+// - SplitWords()
 // - GenerateNGrams()
 // - GeneratePassphrase()
 //
 // ** Validation Functions **
 // - CheckASCIIString()
 // - CheckHexString()
+// - DecodeHexString()
 // - CheckAreMapsEqual()
 // - CheckAreArraysEqual()
 // - IsValidURL()
@@ -37,57 +102,1607 @@ import (
 // ----------------------------------------------------------------------------
 // Functions without Unit Tests
 // ----------------------------------------------------------------------------
-// - ReadURLsToMap() (Loading and Processing Functions)
+// - CollectURLs() (Loading and Processing Functions)
+// - NewHTTPClient() (Loading and Processing Functions)
+// - GetRetryDelay() (Loading and Processing Functions)
+// - GetCachePath() (Loading and Processing Functions)
+// - WriteCachedResponse() (Loading and Processing Functions)
+// - MatchCrawlPatterns() (Loading and Processing Functions)
+// - ExtractResponseLines() (Loading and Processing Functions)
+// - IsExtractedContentType() (Loading and Processing Functions)
 // - ProcessURL() (Loading and Processing Functions)
 // - ProcessURLFile() (Loading and Processing Functions)
 // - GetFilesInDirectory() (Loading and Processing Functions)
 // - IsValidFile() (Validation Functions)
 // - IsFileSystemDirectory() (Validation Functions)
 
-// Unit Test for ReadFilesToMap()
-func TestReadFilesToMap(t *testing.T) {
+// Unit Test for ReadFilesToMap()
+func TestReadFilesToMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input1 string
+		Input2 string
+		Output map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Create a mock file system with example files
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"file1":  []byte("love1\nlove2\nlove3"),
+			"file2":  []byte("<31\n<32\n<33"),
+			"file3":  []byte("爱1\n爱2\n爱3"),
+			"file4":  []byte("amor1\namor2\namor3"),
+			"file5":  []byte("amour1\namour2\namour3"),
+			"file6":  []byte("愛1\n愛2\n愛3"),
+			"file7":  compressTestData(t, "gzip", "love1\nlove2\nlove3"),
+			"file8":  bzip2TestData,
+			"file9":  compressTestData(t, "xz", "爱1\n爱2\n爱3"),
+			"file10": compressTestData(t, "zstd", "<31\n<32\n<33"),
+		},
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{"file1", "file2", map[string]int{"love1": 1, "love2": 1, "love3": 1, "<31": 1, "<32": 1, "<33": 1}},
+		{"file3", "file4", map[string]int{"爱1": 1, "爱2": 1, "爱3": 1, "amor1": 1, "amor2": 1, "amor3": 1}},
+		{"file5", "file6", map[string]int{"amour1": 1, "amour2": 1, "amour3": 1, "愛1": 1, "愛2": 1, "愛3": 1}},
+		{"file1", "file1", map[string]int{"love1": 2, "love2": 2, "love3": 2}},
+		{"file2", "file2", map[string]int{"<31": 2, "<32": 2, "<33": 2}},
+		{"file3", "file3", map[string]int{"爱1": 2, "爱2": 2, "爱3": 2}},
+		{"file7", "file8", map[string]int{"love1": 2, "love2": 2, "love3": 2}},
+		{"file7", "file1", map[string]int{"love1": 2, "love2": 2, "love3": 2}},
+		{"file9", "file10", map[string]int{"爱1": 1, "爱2": 1, "爱3": 1, "<31": 1, "<32": 1, "<33": 1}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input1 := testCase.Input1
+		input2 := testCase.Input2
+		output := testCase.Output
+
+		given := ReadFilesToMap(mockFs, []string{input1, input2}, DefaultMaxLineLength, "", false, nil, nil)
+		if CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ReadFilesToMap(%v, %v) = %v; want %v", input1, input2, given, output)
+		}
+	}
+}
+
+// Unit Test for ReadFileToMap()
+func TestReadFileToMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input         string
+		MaxLineLength int
+		Output        map[string]int
+		Error         bool
+	}
+
+	type TestCases []TestCase
+
+	// Create a mock file system with example files
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"crlf":      []byte("love1\r\nlove2\r\nlove1\r\n"),
+			"trailing":  []byte("love1\nlove2\n\n\nlove2"),
+			"long":      []byte("love1\nlovelovelove\n"),
+			"json":      []byte(`{"love1":2,"love2":3}`),
+			"jsonbom":   []byte("\xef\xbb\xbf  \n{\"love1\":2}"),
+			"jsonl":     []byte("{\"love1\":2}\n{\"love1\":1,\"love2\":1}\n"),
+			"notjson":   []byte("{love1}\n{love1}\nlove2"),
+			"records":   []byte("{\"item\":\"love1\",\"count\":2,\"mask\":\"?l?l?l?l?d\"}\n{\"item\":\"{\\\"x\\\"}\",\"count\":3,\"sources\":[\"a.txt\"]}\n"),
+			"hexrecord": []byte("{\"item\":\"$HEX[636166e9]\",\"count\":2}\n"),
+			"itemkey":   []byte(`{"item":2,"count":3}`),
+			"csv":       []byte("item,count,mask\nlove1,2,?l?l?l?l?d\n\"a,\"\"b\"\"\",3,?l?s?s?l?s\n"),
+			"hexcsv":    []byte("item,count\n$HEX[636166e9],2\n$HEX[244845585b363836395d],1\n"),
+			"notcsv":    []byte("item,count\nlove1,two\n"),
+			"badcsv":    append([]byte("item,count\n"+strings.Repeat("love1,1\n", RecordSniffLength/8+1)), "love2,two\n"...),
+			"badjson":   append(bytes.Repeat([]byte("{\"item\":\"love1\",\"count\":1}\n"), RecordSniffLength/20), "{love2}\n"...),
+			"jsongzip":  compressTestData(t, "gzip", `{"love1":2,"love2":3}`),
+			"chunkedup": bytes.Repeat([]byte("love1\n"), 20000),
+		},
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{"crlf", DefaultMaxLineLength, map[string]int{"love1": 2, "love2": 1}, false},
+		{"trailing", DefaultMaxLineLength, map[string]int{"love1": 1, "love2": 2}, false},
+		{"long", 12, map[string]int{"love1": 1, "lovelovelove": 1}, false},
+		{"long", 11, map[string]int{"love1": 1}, false},
+		{"json", DefaultMaxLineLength, map[string]int{"love1": 2, "love2": 3}, false},
+		{"jsonbom", DefaultMaxLineLength, map[string]int{"love1": 2}, false},
+		{"jsonl", DefaultMaxLineLength, map[string]int{"love1": 3, "love2": 1}, false},
+		{"notjson", DefaultMaxLineLength, map[string]int{"{love1}": 2, "love2": 1}, false},
+		{"records", DefaultMaxLineLength, map[string]int{"love1": 2, "{\"x\"}": 3}, false},
+		{"hexrecord", DefaultMaxLineLength, map[string]int{"caf\xe9": 2}, false},
+		{"itemkey", DefaultMaxLineLength, map[string]int{"item": 2, "count": 3}, false},
+		{"csv", DefaultMaxLineLength, map[string]int{"love1": 2, "a,\"b\"": 3}, false},
+		{"hexcsv", DefaultMaxLineLength, map[string]int{"caf\xe9": 2, "$HEX[6869]": 1}, false},
+		{"notcsv", DefaultMaxLineLength, map[string]int{"item,count": 1, "love1,two": 1}, false},
+		{"badcsv", DefaultMaxLineLength, nil, true},
+		{"badjson", DefaultMaxLineLength, nil, true},
+		{"jsongzip", DefaultMaxLineLength, map[string]int{"love1": 2, "love2": 3}, false},
+		{"chunkedup", 0, map[string]int{"love1": 20000}, false},
+		{"missing", DefaultMaxLineLength, nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given, err := ReadFileToMap(mockFs, input, testCase.MaxLineLength, "", false)
+		if (err != nil) != testCase.Error {
+			t.Errorf("ReadFileToMap(%v, %v) error = %v; want error %v", input, testCase.MaxLineLength, err, testCase.Error)
+			continue
+		}
+		if err == nil && CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ReadFileToMap(%v, %v) = %v; want %v", input, testCase.MaxLineLength, given, output)
+		}
+	}
+}
+
+// Unit Test for IsJSONInput()
+func TestIsJSONInput(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{`{"love1":1}`, true},
+		{"\n\t {\"love1\":1}", true},
+		{"\xef\xbb\xbf{\"love1\":1}", true},
+		{"love1\n{\"love1\":1}", false},
+		{"[1,2]", false},
+		{"", false},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given := IsJSONInput(bufio.NewReader(strings.NewReader(input)))
+		if given != output {
+			t.Errorf("IsJSONInput(%q) = %v; want %v", input, given, output)
+		}
+	}
+}
+
+// Unit Test for IsCSVRecordInput()
+func TestIsCSVRecordInput(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"item,count\nlove1,1", true},
+		{"item,count\r\nlove1,1", true},
+		{"\xef\xbb\xbfitem,count,mask\nlove1,1,?l?l?l?l?d", true},
+		{"item,count", true},
+		{"item,counter\nlove1,1", false},
+		{"love1\nitem,count", false},
+		{"", false},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given := IsCSVRecordInput(bufio.NewReader(strings.NewReader(input)))
+		if given != output {
+			t.Errorf("IsCSVRecordInput(%q) = %v; want %v", input, given, output)
+		}
+	}
+}
+
+// Unit Test for ReadCSVRecordsToMap()
+func TestReadCSVRecordsToMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output map[string]int
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"item,count\nlove1,2\nlove2,1\nlove1,3\n", map[string]int{"love1": 5, "love2": 1}, false},
+		{"\xef\xbb\xbfitem,count,sources\n\" love1\",2,a.txt;b.txt\n,1,stdin\n", map[string]int{" love1": 2, "": 1}, false},
+		{"item,count\n", map[string]int{}, false},
+		{"item,count\nlove1,two\n", nil, true},
+		{"item,count\nlove1\n", nil, true},
+		{"word,count\nlove1,2\n", nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given, err := ReadCSVRecordsToMap(strings.NewReader(input))
+		if (err != nil) != testCase.Error {
+			t.Errorf("ReadCSVRecordsToMap(%q) error = %v; want error %v", input, err, testCase.Error)
+			continue
+		}
+		if err == nil && CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ReadCSVRecordsToMap(%q) = %v; want %v", input, given, output)
+		}
+	}
+}
+
+// Unit Test for ReadLinesToMap()
+func TestReadLinesToMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input         string
+		MaxLineLength int
+		Output        map[string]int
+		Skipped       int64
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"love1\nlove2\nlove1", 5, map[string]int{"love1": 2, "love2": 1}, 0},
+		{"love1\r\nlove2\r\n", 5, map[string]int{"love1": 1, "love2": 1}, 0},
+		{"love1\r", 5, map[string]int{"love1": 1}, 0},
+		{"love1\nlove22", 5, map[string]int{"love1": 1}, 1},
+		{"\n\n\n", 5, map[string]int{}, 0},
+		{"", 5, map[string]int{}, 0},
+		{"爱1\n爱2", 4, map[string]int{"爱1": 1, "爱2": 1}, 0},
+		{"爱1\n爱2", 3, map[string]int{}, 2},
+		{"love1\n" + strings.Repeat("x", 100) + "\nlove2\r\n", 5, map[string]int{"love1": 1, "love2": 1}, 1},
+		{"xxxxxxx\r\nlove1", 5, map[string]int{"love1": 1}, 1},
+		{strings.Repeat("x", 100), 5, map[string]int{}, 1},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		skipped := GetSkippedLineCount()
+		given, err := ReadLinesToMap(strings.NewReader(input), testCase.MaxLineLength)
+		if err != nil {
+			t.Errorf("ReadLinesToMap(%q, %v) error = %v", input, testCase.MaxLineLength, err)
+			continue
+		}
+		if CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ReadLinesToMap(%q, %v) = %v; want %v", input, testCase.MaxLineLength, given, output)
+		}
+		if given := GetSkippedLineCount() - skipped; given != testCase.Skipped {
+			t.Errorf("ReadLinesToMap(%q, %v) skipped %d lines; want %d", input, testCase.MaxLineLength, given, testCase.Skipped)
+		}
+	}
+}
+
+// Unit Test for ParsePotfileLine()
+func TestParsePotfileLine(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input     string
+		Hash      string
+		HashType  string
+		Plaintext string
+		Ok        bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"5f4dcc3b5aa765d61d8327deb882cf99:password", "5f4dcc3b5aa765d61d8327deb882cf99", "md5/ntlm", "password", true},
+		{"5f4dcc3b5aa765d61d8327deb882cf99:$HEX[70613a7373]", "5f4dcc3b5aa765d61d8327deb882cf99", "md5/ntlm", "pa:ss", true},
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:$HEX[706173733a776f7264]", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "sha1", "pass:word", true},
+		{"5f4dcc3b5aa765d61d8327deb882cf99:NaCl:password", "5f4dcc3b5aa765d61d8327deb882cf99:NaCl", "md5-salted", "password", true},
+		{"5f4dcc3b5aa765d61d8327deb882cf99::password", "5f4dcc3b5aa765d61d8327deb882cf99:", "md5-salted", "password", true},
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:salt:$HEX[70613a7373]", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:salt", "sha1-salted", "pa:ss", true},
+		{"$NT$8846f7eaee8fc117ad06bdcce66e4b4a:password", "$NT$8846f7eaee8fc117ad06bdcce66e4b4a", "ntlm", "password", true},
+		{"$dynamic_0$5f4dcc3b5aa765d61d8327deb882cf99:password", "$dynamic_0$5f4dcc3b5aa765d61d8327deb882cf99", "dynamic_0", "password", true},
+		{"$2b$05$LhayLxezLhK1LhWvKxCyLOj0j1u.Kj0jZ0pEmm134uzrQlFvQJLF6:hashcat", "$2b$05$LhayLxezLhK1LhWvKxCyLOj0j1u.Kj0jZ0pEmm134uzrQlFvQJLF6", "bcrypt", "hashcat", true},
+		{"admin::CORP:1122334455667788:0123456789abcdef0123456789abcdef:0101000000:Summer2024", "admin::CORP:1122334455667788:0123456789abcdef0123456789abcdef:0101000000", "netntlmv2", "Summer2024", true},
+		{"abc123:salt:爱1", "abc123:salt", "unknown", "爱1", true},
+		{"password", "", "", "", false},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+
+		hash, hashType, plaintext, ok := ParsePotfileLine(input)
+		if hash != testCase.Hash || hashType != testCase.HashType || plaintext != testCase.Plaintext || ok != testCase.Ok {
+			t.Errorf("ParsePotfileLine(%v) = %v, %v, %v, %v; want %v, %v, %v, %v", input, hash, hashType, plaintext, ok, testCase.Hash, testCase.HashType, testCase.Plaintext, testCase.Ok)
+		}
+	}
+}
+
+// Unit Test for ParsePotfileMap()
+func TestParsePotfileMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input     map[string]int
+		HashTypes map[string][]string
+		Output    map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{map[string]int{"5f4dcc3b5aa765d61d8327deb882cf99:password": 1, "$NT$8846f7eaee8fc117ad06bdcce66e4b4a:password": 2, "password": 1}, nil, map[string]int{"password": 3}},
+		{map[string]int{"5f4dcc3b5aa765d61d8327deb882cf99:password": 1, "$NT$8846f7eaee8fc117ad06bdcce66e4b4a:password": 2, "$NT$8846f7eaee8fc117ad06bdcce66e4b4b:password": 1, "0123456789abcdef0123456789abcdef:爱1": 1}, map[string][]string{"password": {"md5/ntlm", "ntlm"}, "爱1": {"md5/ntlm"}}, map[string]int{"password": 4, "爱1": 1}},
+		{map[string]int{"5f4dcc3b5aa765d61d8327deb882cf99:": 1, "5f4dcc3b5aa765d61d8327deb882cf99:$HEX[e788b131]": 1}, nil, map[string]int{"爱1": 1}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		var hashTypes map[string][]string
+		if testCase.HashTypes != nil {
+			hashTypes = make(map[string][]string)
+		}
+
+		given := ParsePotfileMap(input, hashTypes)
+		if CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ParsePotfileMap(%v) = %v; want %v", input, given, output)
+		}
+		for _, types := range hashTypes {
+			sort.Strings(types)
+		}
+		if !reflect.DeepEqual(hashTypes, testCase.HashTypes) {
+			t.Errorf("ParsePotfileMap(%v) hash types = %v; want %v", input, hashTypes, testCase.HashTypes)
+		}
+	}
+}
+
+// Unit Test for ParseCredentialLine()
+func TestParseCredentialLine(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output models.Credential
+		Ok     bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"Administrator:500:aad3b435b51404eeaad3b435b51404ee:8846F7EAEE8FC117AD06BDCCE66E4B4A:::", models.Credential{Username: "Administrator", RID: "500", LMHash: "aad3b435b51404eeaad3b435b51404ee", NTHash: "8846f7eaee8fc117ad06bdcce66e4b4a"}, true},
+		{"CORP.LOCAL\\alice:1104:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a::: (status=Enabled)", models.Credential{Domain: "CORP.LOCAL", Username: "alice", RID: "1104", LMHash: "aad3b435b51404eeaad3b435b51404ee", NTHash: "8846f7eaee8fc117ad06bdcce66e4b4a"}, true},
+		{"alice:abc:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a:::", models.Credential{}, false},
+		{"alice:1104:aad3b435b51404eeaad3b435b51404ee:8846f7:::", models.Credential{}, false},
+		{"8846f7eaee8fc117ad06bdcce66e4b4a:password", models.Credential{}, false},
+		{"CORP.LOCAL\\alice_history0:1104:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a:::", models.Credential{}, false},
+		{"alice_history12:1104:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a:::", models.Credential{}, false},
+		{"svc_history:1105:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a:::", models.Credential{Username: "svc_history", RID: "1105", LMHash: "aad3b435b51404eeaad3b435b51404ee", NTHash: "8846f7eaee8fc117ad06bdcce66e4b4a"}, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+
+		given, ok := ParseCredentialLine(input)
+		if given != testCase.Output || ok != testCase.Ok {
+			t.Errorf("ParseCredentialLine(%v) = %v, %v; want %v, %v", input, given, ok, testCase.Output, testCase.Ok)
+		}
+	}
+}
+
+// Unit Test for JoinCredentialsWithPotfile() and CredentialsToMap()
+func TestJoinCredentialsWithPotfile(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input   map[string]int
+		Potfile map[string]int
+		Output  map[string]int
+		Cracked int
+	}
+
+	type TestCases []TestCase
+
+	dump := map[string]int{
+		"CORP\\alice:1104:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a:::": 1,
+		"CORP\\bob:1105:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fc117ad06bdcce66e4b4a:::":   1,
+		"CORP\\carol:1106:aad3b435b51404eeaad3b435b51404ee:31d6cfe0d16ae931b73c59d7e0c089c0:::": 1,
+		"CORP\\dave:1107:aad3b435b51404eeaad3b435b51404ee:11111111111111111111111111111111:::":  1,
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{dump, map[string]int{}, map[string]int{}, 0},
+		{dump, map[string]int{"8846F7EAEE8FC117AD06BDCCE66E4B4A:password": 1}, map[string]int{"password": 2}, 2},
+		{dump, map[string]int{"$NT$8846f7eaee8fc117ad06bdcce66e4b4a:$HEX[e788b131]": 1, "31d6cfe0d16ae931b73c59d7e0c089c0:": 1}, map[string]int{"爱1": 2}, 3},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		credentials := JoinCredentialsWithPotfile(ParseCredentialMap(input), testCase.Potfile)
+		cracked := 0
+		for _, credential := range credentials {
+			if credential.Cracked {
+				cracked++
+			}
+		}
+
+		given := CredentialsToMap(credentials)
+		if CheckAreMapsEqual(given, output) == false || cracked != testCase.Cracked {
+			t.Errorf("JoinCredentialsWithPotfile(%v) = %v, %d cracked; want %v, %d cracked", testCase.Potfile, given, cracked, output, testCase.Cracked)
+		}
+	}
+}
+
+// Unit Test for SplitColumnSelector()
+func TestSplitColumnSelector(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input    string
+		Path     string
+		Selector string
+	}
+
+	type TestCases []TestCase
+
+	// Create a real file with a # in the name
+	existing := filepath.Join(t.TempDir(), "wordlist#2024")
+	if err := os.WriteFile(existing, []byte("love1\n"), 0644); err != nil {
+		t.Fatalf("os.WriteFile() error: %v", err)
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{existing, existing, ""},
+		{existing + "#1", existing, "1"},
+		{"data.csv#3", "data.csv", "3"},
+		{"data.jsonl#password", "data.jsonl", "password"},
+		{"dir/data#1.csv#user.password", "dir/data#1.csv", "user.password"},
+		{"data.csv", "data.csv", ""},
+		{"data.csv#", "data.csv#", ""},
+		{"dir#1/data.csv", "dir#1/data.csv", ""},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+
+		path, selector := SplitColumnSelector(&models.RealFileSystem{}, input)
+		if path != testCase.Path || selector != testCase.Selector {
+			t.Errorf("SplitColumnSelector(%v) = %v, %v; want %v, %v", input, path, selector, testCase.Path, testCase.Selector)
+		}
+	}
+
+	// Check existing files on the mock file system
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"wordlist#2024": []byte("love1\n"),
+			"data.csv":      []byte("love1,love2\n"),
+		},
+	}
+	mockCases := TestCases{
+		{"wordlist#2024", "wordlist#2024", ""},
+		{"wordlist#2024#1", "wordlist#2024", "1"},
+		{"data.csv#2", "data.csv", "2"},
+	}
+	for _, testCase := range mockCases {
+		input := testCase.Input
+
+		path, selector := SplitColumnSelector(mockFs, input)
+		if path != testCase.Path || selector != testCase.Selector {
+			t.Errorf("SplitColumnSelector(%v) = %v, %v; want %v, %v", input, path, selector, testCase.Path, testCase.Selector)
+		}
+	}
+
+	// Read the mock files through ReadFilesToMap
+	given := ReadFilesToMap(mockFs, []string{"wordlist#2024", "data.csv#2"}, DefaultMaxLineLength, "", false, nil, nil)
+	if CheckAreMapsEqual(given, map[string]int{"love1": 1, "love2": 1}) == false {
+		t.Errorf("ReadFilesToMap(%v) = %v; want %v", mockCases, given, map[string]int{"love1": 1, "love2": 1})
+	}
+
+	// Read the real file through ReadFilesToMap
+	given = ReadFilesToMap(&models.RealFileSystem{}, []string{existing}, DefaultMaxLineLength, "", false, nil, nil)
+	if CheckAreMapsEqual(given, map[string]int{"love1": 1}) == false {
+		t.Errorf("ReadFilesToMap(%v) = %v; want %v", existing, given, map[string]int{"love1": 1})
+	}
+}
+
+// Unit Test for SplitSourceWeight()
+func TestSplitSourceWeight(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Source string
+		Weight float64
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"client.txt", "client.txt", 1, false},
+		{"client.txt:10", "client.txt", 10, false},
+		{"rockyou.txt:0.01", "rockyou.txt", 0.01, false},
+		{"data.csv#password:2.5", "data.csv#password", 2.5, false},
+		{"C:\\lists\\words.txt", "C:\\lists\\words.txt", 1, false},
+		{"notes:draft.txt", "notes:draft.txt", 1, false},
+		{"https://example.com:8080", "https://example.com:8080", 1, false},
+		{"https://example.com:8080/about:3", "https://example.com:8080/about", 3, false},
+		{"https://example.com:8080:3", "https://example.com:8080", 3, false},
+		{"client.txt:0", "", 0, true},
+		{"client.txt:-2", "", 0, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		source, weight, err := SplitSourceWeight(testCase.Input)
+		if (err != nil) != testCase.Error || source != testCase.Source || weight != testCase.Weight {
+			t.Errorf("SplitSourceWeight(%v) = %v, %v, %v; want %v, %v", testCase.Input, source, weight, err, testCase.Source, testCase.Weight)
+		}
+	}
+}
+
+// Unit Test for ReadColumnToMap()
+func TestReadColumnToMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Header bool
+		Output map[string]int
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Create a mock file system with example files
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"data.csv":      []byte("id,User,password\r\n1,a,\"pass,word\"\r\n2,b,\"say \"\"hi\"\"\"\r\n3,c,\"multi\nline\"\r\n4,d\r\n5,e,pass,word\r\n"),
+			"headless.csv":  []byte("a,love1\nb,love2\n"),
+			"data.tsv":      []byte("user\tpassword\na\tlove1\nb\t\"love\t2\"\n"),
+			"data.txt":      []byte("user\tpassword\na\tlove1\n"),
+			"data.jsonl":    []byte("{\"user\":\"a\",\"password\":\"love1\"}\n\n{\"user\":\"b\",\"password\":12}\n{\"user\":\"c\"}\n{\"user\":\"d\",\"password\":\"love1\"}\n"),
+			"nested.log":    []byte("{\"user\":{\"name\":\"a\",\"password\":\"爱1\"}}\n{\"user\":\"b\"}\n"),
+			"data.csv.gz":   compressTestData(t, "gzip", "user,password\na,love1\n"),
+			"invalid.jsonl": []byte("{\"password\":\"love1\"}\nlove2\n"),
+		},
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{"data.csv#3", true, map[string]int{"pass,word": 1, "pass": 1, "say \"hi\"": 1, "multi\nline": 1}, false},
+		{"headless.csv#2", false, map[string]int{"love1": 1, "love2": 1}, false},
+		{"data.csv#password", false, map[string]int{"pass,word": 1, "pass": 1, "say \"hi\"": 1, "multi\nline": 1}, false},
+		{"data.csv#password", true, map[string]int{"pass,word": 1, "pass": 1, "say \"hi\"": 1, "multi\nline": 1}, false},
+		{"data.csv#user", false, map[string]int{"a": 1, "b": 1, "c": 1, "d": 1, "e": 1}, false},
+		{"data.csv#email", false, nil, true},
+		{"data.csv#0", true, nil, true},
+		{"data.tsv#password", false, map[string]int{"love1": 1, "love\t2": 1}, false},
+		{"data.txt#2", true, map[string]int{"love1": 1}, false},
+		{"data.jsonl#password", true, map[string]int{"love1": 2, "12": 1}, false},
+		{"nested.log#user.password", false, map[string]int{"爱1": 1}, false},
+		{"data.csv.gz#password", false, map[string]int{"love1": 1}, false},
+		{"invalid.jsonl#password", false, nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given, err := ReadFileToMap(mockFs, input, DefaultMaxLineLength, "", testCase.Header)
+		if (err != nil) != testCase.Error {
+			t.Errorf("ReadFileToMap(%v, %v) error = %v; want error %v", input, testCase.Header, err, testCase.Error)
+			continue
+		}
+		if err == nil && CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ReadFileToMap(%v, %v) = %v; want %v", input, testCase.Header, given, output)
+		}
+	}
+}
+
+// bzip2TestData is a fixed bzip2 stream of "love1\nlove2\nlove3" since
+// compress/bzip2 can only decompress
+var bzip2TestData = []byte{66, 90, 104, 57, 49, 65, 89, 38, 83, 89, 32, 44, 137, 233, 0, 0, 4, 73, 128, 0, 16, 56, 0, 2, 4, 129, 0, 32, 0, 34, 61, 67, 38, 132, 48, 34, 241, 16, 110, 184, 135, 139, 185, 34, 156, 40, 72, 16, 22, 68, 244, 128}
+
+// compressTestData compresses the input with the named format for tests
+func compressTestData(t *testing.T, format string, input string) []byte {
+	var buffer bytes.Buffer
+
+	switch format {
+	case "gzip":
+		writer := gzip.NewWriter(&buffer)
+		writer.Write([]byte(input))
+		writer.Close()
+	case "xz":
+		writer, err := xz.NewWriter(&buffer)
+		if err != nil {
+			t.Fatalf("xz.NewWriter() error: %v", err)
+		}
+		writer.Write([]byte(input))
+		writer.Close()
+	case "zstd":
+		writer, err := zstd.NewWriter(&buffer)
+		if err != nil {
+			t.Fatalf("zstd.NewWriter() error: %v", err)
+		}
+		writer.Write([]byte(input))
+		writer.Close()
+	}
+
+	return buffer.Bytes()
+}
+
+// Unit Test for GetDecompressedReader()
+func TestGetDecompressedReader(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  []byte
+		Output string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]byte("love1\nlove2\nlove3"), "love1\nlove2\nlove3"},
+		{[]byte(""), ""},
+		{[]byte("BZ"), "BZ"},
+		{compressTestData(t, "gzip", "love1\nlove2\nlove3"), "love1\nlove2\nlove3"},
+		{bzip2TestData, "love1\nlove2\nlove3"},
+		{compressTestData(t, "xz", "爱1\n爱2\n爱3"), "爱1\n爱2\n爱3"},
+		{compressTestData(t, "zstd", "<31\n<32\n<33"), "<31\n<32\n<33"},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		reader, err := GetDecompressedReader(bytes.NewReader(input))
+		if err != nil {
+			t.Errorf("GetDecompressedReader(%v) error: %v", input, err)
+			continue
+		}
+
+		given, err := io.ReadAll(reader)
+		if err != nil || string(given) != output {
+			t.Errorf("GetDecompressedReader(%v) = %v; want %v", input, string(given), output)
+		}
+	}
+}
+
+// Unit Test for GetDecodedReader()
+func TestGetDecodedReader(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input    []byte
+		Encoding string
+		Output   string
+		Error    bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]byte("caf\xe9"), "", "caf\xe9", false},
+		{[]byte("caf\xe9"), "latin1", "café", false},
+		{[]byte("caf\xe9\x80"), "windows-1252", "café€", false},
+		{[]byte("caf\xe9"), "auto", "café", false},
+		{[]byte("\xef\xbb\xbfcafé"), "auto", "café", false},
+		{[]byte("\xff\xfe\x31\x72\x0a\x00"), "auto", "爱\n", false},
+		{[]byte("l\x00o\x00v\x00e\x00"), "auto", "love", false},
+		{[]byte("\x00l\x00o\x00v\x00e"), "auto", "love", false},
+		{[]byte("l\x00o\x00v\x00e\x00"), "utf-16le", "love", false},
+		{[]byte("\x88\xa4\x82\xb5\x82\xc4\x82\xe9"), "shift_jis", "愛してる", false},
+		{[]byte("\x88\xa4\x82\xb5\x82\xc4\x82\xe9"), "auto", "愛してる", false},
+		{append(bytes.Repeat([]byte("love1\n"), 1000), "caf\xe9\n爱\n"...), "auto", strings.Repeat("love1\n", 1000) + "café\n爱\n", false},
+		{append(bytes.Repeat([]byte("é"), 4000), "\x80"...), "auto", strings.Repeat("é", 4000) + "€", false},
+		{[]byte("love"), "bogus", "", true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		reader, err := GetDecodedReader(bytes.NewReader(input), testCase.Encoding)
+		if (err != nil) != testCase.Error {
+			t.Errorf("GetDecodedReader(%v, %v) error = %v; want error %v", input, testCase.Encoding, err, testCase.Error)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		given, err := io.ReadAll(reader)
+		if err != nil || string(given) != output {
+			t.Errorf("GetDecodedReader(%v, %v) = %q; want %q", input, testCase.Encoding, string(given), output)
+		}
+	}
+}
+
+// Unit Test for DetectEncoding()
+func TestDetectEncoding(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  []byte
+		Output string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]byte(""), "utf-8"},
+		{[]byte("love1"), "utf-8"},
+		{[]byte("爱1"), "utf-8"},
+		{[]byte("\xef\xbb\xbflove1"), "utf-8"},
+		{[]byte("\xff\xfel\x00"), "utf-16le"},
+		{[]byte("\xfe\xff\x00l"), "utf-16be"},
+		{[]byte("l\x00o\x00v\x00e\x001\x00"), "utf-16le"},
+		{[]byte("\x00l\x00o\x00v\x00e\x001"), "utf-16be"},
+		{[]byte("\x88\xa4\x82\xb5\x82\xc4\x82\xe9"), "shift_jis"},
+		{[]byte("caf\xe9"), "windows-1252"},
+		{[]byte("na\xefve caf\xe9"), "windows-1252"},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given := DetectEncoding(input)
+		if given != output {
+			t.Errorf("DetectEncoding(%v) = %v; want %v", input, given, output)
+		}
+	}
+}
+
+// createTestArchive creates a zip or gzip compressed tar archive of the files
+// for tests
+func createTestArchive(t *testing.T, format string, files [][2]string) []byte {
+	var buffer bytes.Buffer
+
+	switch format {
+	case "zip":
+		writer := zip.NewWriter(&buffer)
+		for _, file := range files {
+			member, err := writer.Create(file[0])
+			if err != nil {
+				t.Fatalf("zip.Create() error: %v", err)
+			}
+			member.Write([]byte(file[1]))
+		}
+		writer.Close()
+	case "tar.gz":
+		compressor := gzip.NewWriter(&buffer)
+		writer := tar.NewWriter(compressor)
+		writer.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755})
+		for _, file := range files {
+			writer.WriteHeader(&tar.Header{Name: file[0], Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(file[1]))})
+			writer.Write([]byte(file[1]))
+		}
+		writer.Close()
+		compressor.Close()
+	}
+
+	return buffer.Bytes()
+}
+
+// Unit Test for MatchArchiveGlobs()
+func TestMatchArchiveGlobs(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input   string
+		Include []string
+		Exclude []string
+		Output  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"dir/love.txt", nil, nil, true},
+		{"dir/love.txt", []string{"*.txt"}, nil, true},
+		{"dir/love.txt", []string{"dir/*.txt"}, nil, true},
+		{"dir/love.csv", []string{"*.txt"}, nil, false},
+		{"dir/love.txt", nil, []string{"love*"}, false},
+		{"dir/love.txt", []string{"*.txt", "*.csv"}, []string{"dir/*"}, false},
+		{"dir/readme.md", []string{"*.txt"}, []string{"*.md"}, false},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+
+		given := MatchArchiveGlobs(input, testCase.Include, testCase.Exclude)
+		if given != testCase.Output {
+			t.Errorf("MatchArchiveGlobs(%v, %v, %v) = %v; want %v", input, testCase.Include, testCase.Exclude, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for WalkArchive() through ReadFilesToMap()
+func TestWalkArchive(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input   string
+		Include []string
+		Exclude []string
+		Output  map[string]int
+	}
+
+	type TestCases []TestCase
+
+	members := [][2]string{
+		{"dir/love.txt", "love1\nlove2\n"},
+		{"dir/爱.txt", "爱1\nlove1\n"},
+		{"data.csv", "user,password\na,love3\n"},
+		{"output.json", `{"love1":2}`},
+	}
+
+	// Create a mock file system with example archives
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"data.zip":    createTestArchive(t, "zip", members),
+			"data.tar.gz": createTestArchive(t, "tar.gz", members),
+		},
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{"data.zip", nil, []string{"*.csv"}, map[string]int{"love1": 4, "love2": 1, "爱1": 1}},
+		{"data.tar.gz", nil, []string{"*.csv"}, map[string]int{"love1": 4, "love2": 1, "爱1": 1}},
+		{"data.zip", []string{"dir/*"}, nil, map[string]int{"love1": 2, "love2": 1, "爱1": 1}},
+		{"data.tar.gz", []string{"*.txt"}, []string{"爱*"}, map[string]int{"love1": 1, "love2": 1}},
+		{"data.zip#password", []string{"*.csv"}, nil, map[string]int{"love3": 1}},
+		{"data.tar.gz#2", []string{"*.csv"}, nil, map[string]int{"love3": 1}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		input := testCase.Input
+		output := testCase.Output
+
+		given := ReadFilesToMap(mockFs, []string{input}, DefaultMaxLineLength, "", true, testCase.Include, testCase.Exclude)
+		if CheckAreMapsEqual(given, output) == false {
+			t.Errorf("ReadFilesToMap(%v, %v, %v) = %v; want %v", input, testCase.Include, testCase.Exclude, given, output)
+		}
+	}
+}
+
+// Unit Test for ReadDocumentsToMap()
+func TestReadDocumentsToMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input       string
+		ParsingMode int
+		JSONPaths   []string
+		Output      map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Create a mock file system with example documents
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"page.html":     []byte("<html><body><p>Hello World. Acme Corporation</p></body></html>"),
+			"notes.txt":     []byte("Summer time, winter time\n"),
+			"docs.zip":      createTestArchive(t, "zip", [][2]string{{"about.html", "<p>Hello World</p>"}, {"skip.txt", "Skipped"}}),
+			"page.html.gz":  compressTestData(t, "gzip", "<p>Compressed page</p>"),
+			"api.json":      []byte(`{"items": [{"title": "Falcon Project", "id": 7}, {"title": "Summer Sale"}], "meta": {"note": "Internal only"}}`),
+			"crawl.warc.gz": compressTestData(t, "gzip", "WARC/1.0\r\nWARC-Type: response\r\nContent-Type: application/http\r\nContent-Length: 64\r\n\r\nHTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<p>Crawled page</p>\n\r\n\r\n"),
+		},
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{"page.html", 0, nil, map[string]int{"Hello World": 1, "Acme Corporation": 1}},
+		{"notes.txt", 0, nil, map[string]int{"Summer time, winter time": 1}},
+		{"notes.txt", 1, nil, map[string]int{"Summer time, winter time": 1, "Summer time": 1, "winter time": 1}},
+		{"docs.zip", 0, nil, map[string]int{"Hello World": 1}},
+		{"page.html.gz", 0, nil, map[string]int{"Compressed page": 1}},
+		{"crawl.warc.gz", 0, nil, map[string]int{"Crawled page": 1}},
+		{"api.json", 0, nil, map[string]int{"Falcon Project": 1, "Summer Sale": 1, "Internal only": 1}},
+		{"api.json", 0, []string{"$.items[*].title"}, map[string]int{"Falcon Project": 1, "Summer Sale": 1}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ReadDocumentsToMap(mockFs, []string{testCase.Input}, testCase.ParsingMode, 0, []string{"*.html"}, nil, models.ExtractOptions{JSONPaths: testCase.JSONPaths})
+		if err != nil || CheckAreMapsEqual(given, testCase.Output) == false {
+			t.Errorf("ReadDocumentsToMap(%v, %v) = %v, %v; want %v", testCase.Input, testCase.ParsingMode, given, err, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ParseLinesToChannel()
+func TestParseLinesToChannel(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Lines       []string
+		Extracted   bool
+		ParsingMode int
+		Punctuation string
+		Output      map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]string{"Hello World. Acme Corporation"}, true, 0, "", map[string]int{"Hello World": 1, "Acme Corporation": 1}},
+		{[]string{"Café crème, s'il vous plaît.", "Bienvenue à Genève."}, true, 0, "", map[string]int{"Bienvenue à Genève": 1}},
+		{[]string{"Café crème, s'il vous plaît."}, true, 1, "", map[string]int{"Café crème, s'il vous plaît": 1, "Café crème": 1, "s'il vous plaît": 1}},
+		{[]string{"東京、大阪。名古屋"}, true, 1, "", map[string]int{"東京、大阪": 1, "東京": 1, "大阪": 1, "名古屋": 1}},
+		{[]string{"one, two; three"}, true, 1, ";", map[string]int{"one, two; three": 1, "one, two": 1, "three": 1}},
+		{[]string{"plain text", "raw <b>text</b>"}, false, 0, "", map[string]int{"raw <b>text</b>": 1}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		ch := make(chan string)
+		go func() {
+			ParseLinesToChannel(testCase.Lines, testCase.Extracted, testCase.ParsingMode, testCase.Punctuation, ch)
+			close(ch)
+		}()
+
+		given := make(map[string]int)
+		for word := range ch {
+			given[word]++
+		}
+		if !CheckAreMapsEqual(given, testCase.Output) {
+			t.Errorf("ParseLinesToChannel(%q, %v, %v, %v) = %v; want %v", testCase.Lines, testCase.Extracted, testCase.ParsingMode, testCase.Punctuation, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ParseRobotsTxt() and IsAllowedByRobots()
+func TestIsAllowedByRobots(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Robots string
+		Path   string
+		Output bool
+	}
+
+	type TestCases []TestCase
+
+	robots := "User-agent: *\nDisallow: /private\nAllow: /private/public\nDisallow: /*.pdf$\n\nUser-agent: other\nDisallow: /\n"
+	specific := "User-agent: *\nDisallow: /\n\nUser-agent: ptt\nDisallow: /admin # comment\n"
+
+	// Define test cases
+	testCases := TestCases{
+		{robots, "/", true},
+		{robots, "/private/data", false},
+		{robots, "/private/public/page", true},
+		{robots, "/files/report.pdf", false},
+		{robots, "/files/report.pdf?download=1", true},
+		{specific, "/about", true},
+		{specific, "/admin/users", false},
+		{"", "/anything", true},
+		{"User-agent: *\nDisallow:\n", "/anything", true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		rules := ParseRobotsTxt(testCase.Robots, "ptt")
+		given := IsAllowedByRobots(rules, testCase.Path)
+		if given != testCase.Output {
+			t.Errorf("IsAllowedByRobots(%v, %v) = %v; want %v", testCase.Robots, testCase.Path, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for CrawlURLs() through ReadURLsToMap()
+func TestCrawlURLs(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Options models.CrawlOptions
+		Output  map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Create a test server with a small site
+	var server *httptest.Server
+	pages := map[string]string{
+		"/":          `<p>Home page</p><a href="/a">A</a><a href="/private/x#top">P</a><a href="https://other.example/">O</a>`,
+		"/a":         `<p>Page A</p><a href="b">B</a>`,
+		"/b":         `<p>Page B</p>`,
+		"/hidden":    `<p>Hidden page</p>`,
+		"/private/x": `<p>Private</p>`,
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /private\nSitemap: %s/map.xml\n", server.URL)
+		case "/map.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<urlset><url><loc>%s/hidden</loc></url><url><loc>https://other.example/</loc></url></urlset>`, server.URL)
+		default:
+			page, ok := pages[r.URL.Path]
+			if !ok {
+				http.Error(w, "missing", http.StatusGone)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, page)
+		}
+	}))
+	defer server.Close()
+
+	// Crawl without rate limits to keep the test fast
+	defer ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: DefaultHTTPTimeout, RequestsPerSecond: DefaultRequestsPerSecond, Concurrency: DefaultConcurrency})
+	if err := ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: 5}); err != nil {
+		t.Fatalf("ConfigureHTTPClient() error: %v", err)
+	}
+
+	home := map[string]int{"Home page": 1, "A": 1, "P": 1, "O": 1}
+	withPages := func(extra map[string]int) map[string]int {
+		return CombineMaps(home, extra)
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{models.CrawlOptions{Depth: 1}, withPages(map[string]int{"Page A": 1, "B": 1})},
+		{models.CrawlOptions{Depth: 2}, withPages(map[string]int{"Page A": 1, "B": 1, "Page B": 1})},
+		{models.CrawlOptions{Depth: 2, MaxPages: 2}, withPages(map[string]int{"Page A": 1, "B": 1})},
+		{models.CrawlOptions{Depth: 2, Exclude: []string{`/b$`}}, withPages(map[string]int{"Page A": 1, "B": 1})},
+		{models.CrawlOptions{Depth: 2, Include: []string{`/private`}}, home},
+		{models.CrawlOptions{Depth: 1, IgnoreRobots: true}, withPages(map[string]int{"Page A": 1, "B": 1, "Private": 1})},
+		{models.CrawlOptions{Sitemap: true}, withPages(map[string]int{"Hidden page": 1})},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := ReadURLsToMap([]string{server.URL + "/"}, 0, 0, nil, nil, testCase.Options, models.ExtractOptions{}, nil)
+		if err != nil || CheckAreMapsEqual(given, testCase.Output) == false {
+			t.Errorf("ReadURLsToMap(%v) = %v, %v; want %v", testCase.Options, given, err, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ReadURLsToMap() with source tracking
+func TestReadURLsToMapSources(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		URLs    []string
+		Options models.CrawlOptions
+		Sources map[string]map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Create a test server with pages sharing a phrase
+	pages := map[string]string{
+		"/":  `<p>Home page</p><a href="/a">Shared</a>`,
+		"/a": `<p>Page A</p>`,
+		"/b": `<p>Page B. Shared</p>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	// Fetch without rate limits to keep the test fast
+	defer ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: DefaultHTTPTimeout, RequestsPerSecond: DefaultRequestsPerSecond, Concurrency: DefaultConcurrency})
+	if err := ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: 5}); err != nil {
+		t.Fatalf("ConfigureHTTPClient() error: %v", err)
+	}
+
+	home, a, b := server.URL+"/", server.URL+"/a", server.URL+"/b"
+
+	// Define test cases
+	testCases := TestCases{
+		{[]string{a, b}, models.CrawlOptions{}, map[string]map[string]int{a: {"Page A": 1}, b: {"Page B": 1, "Shared": 1}}},
+		{[]string{home}, models.CrawlOptions{Depth: 1, IgnoreRobots: true}, map[string]map[string]int{home: {"Home page": 1, "Shared": 1, "Page A": 1}}},
+		{[]string{home, a}, models.CrawlOptions{Depth: 1, IgnoreRobots: true}, map[string]map[string]int{home: {"Home page": 1, "Shared": 1}, a: {"Page A": 1}}},
+		{[]string{home, b}, models.CrawlOptions{Depth: 1, MaxPages: 2, IgnoreRobots: true}, map[string]map[string]int{home: {"Home page": 1, "Shared": 1}, b: {"Page B": 1, "Shared": 1}}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		want, err := ReadURLsToMap(testCase.URLs, 0, 0, nil, nil, testCase.Options, models.ExtractOptions{}, nil)
+		if err != nil {
+			t.Fatalf("ReadURLsToMap(%v) error: %v", testCase.URLs, err)
+		}
+
+		// Tracking sources shares one crawl and does not change the output
+		sources := make(map[string]map[string]int)
+		given, err := ReadURLsToMap(testCase.URLs, 0, 0, nil, nil, testCase.Options, models.ExtractOptions{}, sources)
+		if err != nil || !CheckAreMapsEqual(given, want) || !reflect.DeepEqual(sources, testCase.Sources) {
+			t.Errorf("ReadURLsToMap(%v, %v) = %v, %v, sources %v; want %v, sources %v", testCase.URLs, testCase.Options, given, err, sources, want, testCase.Sources)
+		}
+	}
+}
+
+// Unit Test for ParseHTTPHeader()
+func TestParseHTTPHeader(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input string
+		Name  string
+		Value string
+		Error bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"Authorization: Bearer abc:123", "Authorization", "Bearer abc:123", false},
+		{"X-Empty:", "X-Empty", "", false},
+		{"no separator", "", "", true},
+		{": value", "", "", true},
+		{"Bad Name: value", "", "", true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		name, value, err := ParseHTTPHeader(testCase.Input)
+		if name != testCase.Name || value != testCase.Value || (err != nil) != testCase.Error {
+			t.Errorf("ParseHTTPHeader(%v) = %v, %v, %v; want %v, %v, error %v", testCase.Input, name, value, err, testCase.Name, testCase.Value, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for LoadCookieJar()
+func TestLoadCookieJar(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		URL    string
+		Output string
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Create a mock file system with example cookie files
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"cookies.txt": []byte("# Netscape HTTP Cookie File\n.example.com\tTRUE\t/\tFALSE\t0\tsession\tabc\n#HttpOnly_intranet.local\tFALSE\t/app\tTRUE\t0\ttoken\txyz\n"),
+			"pairs.txt":   []byte("https://example.org/\ta=1; b=2\n"),
+			"invalid.txt": []byte("not a cookie\n"),
+		},
+	}
+
+	// Define test cases
+	testCases := TestCases{
+		{"cookies.txt", "http://www.example.com/page", "session=abc", false},
+		{"cookies.txt", "https://intranet.local/app/home", "token=xyz", false},
+		{"cookies.txt", "http://intranet.local/app/home", "", false},
+		{"pairs.txt", "https://example.org/", "a=1; b=2", false},
+		{"invalid.txt", "", "", true},
+		{"missing.txt", "", "", true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		jar, err := LoadCookieJar(mockFs, testCase.Input)
+		if (err != nil) != testCase.Error {
+			t.Errorf("LoadCookieJar(%v) error = %v; want error %v", testCase.Input, err, testCase.Error)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		target, _ := url.Parse(testCase.URL)
+		var pairs []string
+		for _, cookie := range jar.Cookies(target) {
+			pairs = append(pairs, cookie.Name+"="+cookie.Value)
+		}
+		if given := strings.Join(pairs, "; "); given != testCase.Output {
+			t.Errorf("LoadCookieJar(%v).Cookies(%v) = %v; want %v", testCase.Input, testCase.URL, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for ConfigureHTTPClient() through FetchURL()
+func TestConfigureHTTPClient(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Options models.HTTPOptions
+		Target  string
+		Output  string
+		Error   bool
+	}
+
+	type TestCases []TestCase
+
+	// Create test servers that echo the request and act as a proxy
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(1500 * time.Millisecond)
+		}
+		cookie, _ := r.Cookie("session")
+		fmt.Fprintf(w, "%s|%s|%s|%v", r.Header.Get("User-Agent"), r.Header.Get("X-Test"), r.Host, cookie)
+	})
+	server := httptest.NewServer(echo)
+	defer server.Close()
+	tlsServer := httptest.NewUnstartedServer(echo)
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "proxied %s", r.URL.String())
+	}))
+	defer proxy.Close()
+
+	mockFs := &models.MockFileSystem{
+		Files: map[string][]byte{
+			"cookies.txt": []byte(strings.TrimPrefix(server.URL, "http://") + "\tFALSE\t/\tFALSE\t0\tsession\tabc\n"),
+		},
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	defer ConfigureHTTPClient(mockFs, models.HTTPOptions{Timeout: DefaultHTTPTimeout, RequestsPerSecond: DefaultRequestsPerSecond, Concurrency: DefaultConcurrency})
+
+	// Define test cases
+	testCases := TestCases{
+		{models.HTTPOptions{Timeout: 5, UserAgent: "ptt-test", Headers: []string{"X-Test: yes"}}, server.URL, "ptt-test|yes|" + host + "|", false},
+		{models.HTTPOptions{Timeout: 5, UserAgent: "ptt-test", Headers: []string{"Host: intranet.local"}}, server.URL, "ptt-test||intranet.local|", false},
+		{models.HTTPOptions{Timeout: 5, UserAgent: "ptt-test", CookieFile: "cookies.txt"}, server.URL, "ptt-test||" + host + "|session=abc", false},
+		{models.HTTPOptions{Timeout: 5, Proxy: proxy.URL}, "http://intranet.local/page", "proxied http://intranet.local/page", false},
+		{models.HTTPOptions{Timeout: 5, UserAgent: "ptt-test"}, tlsServer.URL, "", true},
+		{models.HTTPOptions{Timeout: 5, UserAgent: "ptt-test", Insecure: true}, tlsServer.URL, "ptt-test||" + strings.TrimPrefix(tlsServer.URL, "https://") + "|", false},
+		{models.HTTPOptions{Timeout: 1}, server.URL + "/slow", "", true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		if err := ConfigureHTTPClient(mockFs, testCase.Options); err != nil {
+			t.Fatalf("ConfigureHTTPClient(%v) error: %v", testCase.Options, err)
+		}
+
+		given := ""
+		resp, err := FetchURL(testCase.Target)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			given = string(body)
+		}
+		if given != testCase.Output || (err != nil) != testCase.Error {
+			t.Errorf("FetchURL(%v) with %v = %v, %v; want %v, error %v", testCase.Target, testCase.Options, given, err, testCase.Output, testCase.Error)
+		}
+	}
+}
+
+// Unit Test for ParseRetryAfter()
+func TestParseRetryAfter(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output time.Duration
+		Valid  bool
+	}
+
+	type TestCases []TestCase
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Define test cases
+	testCases := TestCases{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 31 Dec 2023 23:00:00 GMT", 0, true},
+		{"86400", 5 * time.Minute, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, valid := ParseRetryAfter(testCase.Input, now)
+		if given != testCase.Output || valid != testCase.Valid {
+			t.Errorf("ParseRetryAfter(%v) = %v, %v; want %v, %v", testCase.Input, given, valid, testCase.Output, testCase.Valid)
+		}
+	}
+}
+
+// Unit Test for the token buckets of hostRateLimiter
+func TestHostRateLimiter(t *testing.T) {
 
 	// Define a test case struct
 	type TestCase struct {
-		Input1 string
-		Input2 string
-		Output map[string]int
+		Host    string
+		Elapsed time.Duration
+		Output  time.Duration
 	}
 
 	type TestCases []TestCase
 
-	// Create a mock file system with example files
-	mockFs := &models.MockFileSystem{
-		Files: map[string][]byte{
-			"file1": []byte("love1\nlove2\nlove3"),
-			"file2": []byte("<31\n<32\n<33"),
-			"file3": []byte("爱1\n爱2\n爱3"),
-			"file4": []byte("amor1\namor2\namor3"),
-			"file5": []byte("amour1\namour2\namour3"),
-			"file6": []byte("愛1\n愛2\n愛3"),
-		},
+	limiter := newHostRateLimiter(2, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Define test cases in order as requests share the buckets
+	testCases := TestCases{
+		{"a.example", 0, 0},
+		{"a.example", 0, 0},
+		{"a.example", 0, 500 * time.Millisecond},
+		{"b.example", 0, 0},
+		{"a.example", 0, time.Second},
+		{"a.example", 3 * time.Second, 0},
 	}
 
-	// Define test cases
+	// Run test cases
+	for i, testCase := range testCases {
+		given := limiter.reserve(testCase.Host, start.Add(testCase.Elapsed))
+		if given != testCase.Output {
+			t.Errorf("reserve(%v) request %d = %v; want %v", testCase.Host, i, given, testCase.Output)
+		}
+	}
+
+	// Unlimited limiters never wait
+	unlimited := newHostRateLimiter(0, 0)
+	for i := 0; i < 5; i++ {
+		if given := unlimited.reserve("a.example", start); given != 0 {
+			t.Errorf("reserve() with no rate limit = %v; want 0", given)
+		}
+	}
+}
+
+// Unit Test for FetchURL() retries and concurrency limits
+func TestFetchURLLimits(t *testing.T) {
+	var mutex sync.Mutex
+	requests, inFlight, maxInFlight := 0, 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		count := requests
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mutex.Unlock()
+
+		defer func() {
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+		}()
+
+		switch r.URL.Path {
+		case "/throttled":
+			if count == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		case "/always":
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			time.Sleep(50 * time.Millisecond)
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer server.Close()
+	defer ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: DefaultHTTPTimeout, RequestsPerSecond: DefaultRequestsPerSecond, Concurrency: DefaultConcurrency})
+
+	if err := ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: 5, Concurrency: 2}); err != nil {
+		t.Fatalf("ConfigureHTTPClient() error: %v", err)
+	}
+
+	// Throttled requests are retried after the Retry-After delay
+	resetURLStats()
+	resp, err := FetchURL(server.URL + "/throttled")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("FetchURL(/throttled) = %v, %v; want 200 OK", resp, err)
+	}
+	resp.Body.Close()
+
+	// Requests that stay throttled give up after the retries
+	resp, err = FetchURL(server.URL + "/always")
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("FetchURL(/always) = %v, %v; want 429 Too Many Requests", resp, err)
+	}
+	resp.Body.Close()
+
+	want := models.URLStats{Fetched: 1, Failed: 1, Throttled: 2}
+	if given := GetURLStats(); given != want {
+		t.Errorf("GetURLStats() = %+v; want %+v", given, want)
+	}
+
+	// Requests at once are limited by the concurrency cap
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := FetchURL(server.URL + "/slow"); err == nil {
+				io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("FetchURL() with a concurrency of 2 sent %d requests at once", maxInFlight)
+	}
+}
+
+// Unit Test for FetchURL() with the response cache
+func TestFetchURLCache(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Path     string
+		Offline  bool
+		Output   string
+		Requests int
+		Error    bool
+	}
+
+	type TestCases []TestCase
+
+	// Create a test server that supports conditional requests
+	var mutex sync.Mutex
+	requests := 0
+	lastModified := "Mon, 01 Jan 2024 00:00:00 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, "etag body")
+		case "/modified":
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", lastModified)
+			fmt.Fprint(w, "modified body")
+		case "/redirect":
+			http.Redirect(w, r, "/etag", http.StatusFound)
+		default:
+			fmt.Fprint(w, "fresh body")
+		}
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	defer ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: DefaultHTTPTimeout, RequestsPerSecond: DefaultRequestsPerSecond, Concurrency: DefaultConcurrency})
+
+	// Define test cases in order as requests share the cache
 	testCases := TestCases{
-		{"file1", "file2", map[string]int{"love1": 1, "love2": 1, "love3": 1, "<31": 1, "<32": 1, "<33": 1}},
-		{"file3", "file4", map[string]int{"爱1": 1, "爱2": 1, "爱3": 1, "amor1": 1, "amor2": 1, "amor3": 1}},
-		{"file5", "file6", map[string]int{"amour1": 1, "amour2": 1, "amour3": 1, "愛1": 1, "愛2": 1, "愛3": 1}},
-		{"file1", "file1", map[string]int{"love1": 2, "love2": 2, "love3": 2}},
-		{"file2", "file2", map[string]int{"<31": 2, "<32": 2, "<33": 2}},
-		{"file3", "file3", map[string]int{"爱1": 2, "爱2": 2, "爱3": 2}},
+		{"/etag", false, "etag body", 1, false},
+		{"/etag", false, "etag body", 1, false},
+		{"/modified", false, "modified body", 1, false},
+		{"/modified", false, "modified body", 1, false},
+		{"/plain", false, "fresh body", 1, false},
+		{"/plain", false, "fresh body", 1, false},
+		{"/redirect", false, "etag body", 2, false},
+		{"/etag", true, "etag body", 0, false},
+		{"/redirect", true, "etag body", 0, false},
+		{"/missing", true, "", 0, true},
 	}
 
 	// Run test cases
 	for _, testCase := range testCases {
-		input1 := testCase.Input1
-		input2 := testCase.Input2
-		output := testCase.Output
+		if err := ConfigureHTTPClient(&models.RealFileSystem{}, models.HTTPOptions{Timeout: 5, CacheDir: cacheDir, Offline: testCase.Offline}); err != nil {
+			t.Fatalf("ConfigureHTTPClient() error: %v", err)
+		}
 
-		given := ReadFilesToMap(mockFs, []string{input1, input2})
-		if CheckAreMapsEqual(given, output) == false {
-			t.Errorf("ReadFilesToMap(%v, %v) = %v; want %v", input1, input2, given, output)
+		mutex.Lock()
+		requests = 0
+		mutex.Unlock()
+
+		given := ""
+		resp, err := FetchURL(server.URL + testCase.Path)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			given = string(body)
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("FetchURL(%v) status = %v; want 200", testCase.Path, resp.StatusCode)
+			}
 		}
+
+		if given != testCase.Output || requests != testCase.Requests || (err != nil) != testCase.Error {
+			t.Errorf("FetchURL(%v) offline %v = %v, %d requests, %v; want %v, %d requests, error %v", testCase.Path, testCase.Offline, given, requests, err, testCase.Output, testCase.Requests, testCase.Error)
+		}
+	}
+
+	// Cached redirects keep the final URL for resolving links
+	resp, err := ReadCachedResponse(cacheDir, server.URL+"/redirect")
+	if err != nil || resp == nil || resp.Request.URL.String() != server.URL+"/etag" {
+		t.Errorf("ReadCachedResponse(/redirect) = %v, %v; want final URL %v", resp, err, server.URL+"/etag")
+	}
+
+	// Concurrent writes of the same URL use separate temporary files
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := ReadCachedResponse(cacheDir, server.URL+"/plain")
+			if err != nil {
+				errs <- err
+				return
+			}
+			_, err = WriteCachedResponse(cacheDir, server.URL+"/plain", resp)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WriteCachedResponse(/plain) concurrently error: %v", err)
+		}
+	}
+
+	leftover, _ := filepath.Glob(filepath.Join(cacheDir, "*.tmp"))
+	if len(leftover) != 0 {
+		t.Errorf("WriteCachedResponse() left temporary files %v", leftover)
 	}
 }
 
@@ -168,6 +1783,160 @@ func TestCombineMaps(t *testing.T) {
 	}
 }
 
+// Unit Test for NormalizeMap()
+func TestNormalizeMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  map[string]int
+		Mode   string
+		Total  int
+		Output map[string]int
+		Error  bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{map[string]int{"love": 3, "amor": 1}, "count", NormalizedTotal, map[string]int{"love": 750000, "amor": 250000}, false},
+		{map[string]int{"love": 2999999, "amor": 1, "爱": 1}, "count", 3000001, map[string]int{"love": 2999999, "amor": 1, "爱": 1}, false},
+		{map[string]int{"love": 50, "amor": 20, "爱": 20, "amour": 1}, "rank", NormalizedTotal, map[string]int{"love": 1000000, "amor": 750000, "爱": 750000, "amour": 250000}, false},
+		{map[string]int{"love": 1}, "rank", NormalizedTotal, map[string]int{"love": 1000000}, false},
+		{map[string]int{"love": 1}, "size", NormalizedTotal, nil, true},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given, err := NormalizeMap(testCase.Input, testCase.Mode, testCase.Total)
+		if (err != nil) != testCase.Error || (!testCase.Error && !CheckAreMapsEqual(given, testCase.Output)) {
+			t.Errorf("NormalizeMap(%v, %v, %v) = %v, %v; want %v", testCase.Input, testCase.Mode, testCase.Total, given, err, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for GetNormalizedTotal()
+func TestGetNormalizedTotal(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Sources []map[string]int
+		Mode    string
+		Output  int
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]map[string]int{{"love": 3, "amor": 1}, {"爱": 10}}, "count", NormalizedTotal},
+		{[]map[string]int{{"love": 2999999, "amor": 1}, {"爱": 10}}, "count", 3000000},
+		{[]map[string]int{{"love": 2999999, "amor": 1}, {"爱": 10}}, "rank", NormalizedTotal},
+		{nil, "rank", NormalizedTotal},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := GetNormalizedTotal(testCase.Sources, testCase.Mode)
+		if given != testCase.Output {
+			t.Errorf("GetNormalizedTotal(%v, %v) = %v; want %v", testCase.Sources, testCase.Mode, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for WeightMap()
+func TestWeightMap(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  map[string]int
+		Weight float64
+		Output map[string]int
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{map[string]int{"love": 3, "爱": 1}, 10, map[string]int{"love": 30, "爱": 10}},
+		{map[string]int{"love": 250, "爱": 1}, 0.01 * GetWeightScale([]float64{10, 0.01}), map[string]int{"love": 250, "爱": 1}},
+		{map[string]int{"love": 250, "爱": 1}, 10 * GetWeightScale([]float64{10, 0.01}), map[string]int{"love": 250000, "爱": 1000}},
+		{map[string]int{"love": 3}, 1, map[string]int{"love": 3}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := WeightMap(testCase.Input, testCase.Weight)
+		if !CheckAreMapsEqual(given, testCase.Output) {
+			t.Errorf("WeightMap(%v, %v) = %v; want %v", testCase.Input, testCase.Weight, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for GetWeightScale()
+func TestGetWeightScale(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Weights []float64
+		Output  float64
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]float64{10, 2}, 1},
+		{[]float64{10, 0.01}, 100},
+		{[]float64{0.5, 0.25}, 4},
+		{nil, 1},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := GetWeightScale(testCase.Weights)
+		if math.Abs(given-testCase.Output) > 1e-9 {
+			t.Errorf("GetWeightScale(%v) = %v; want %v", testCase.Weights, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for AddProvenance()
+func TestAddProvenance(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Sources []string
+		Maps    []map[string]int
+		Output  map[string][]string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{[]string{"a.txt"}, []map[string]int{{"love": 1, "爱": 2}}, map[string][]string{"love": {"a.txt"}, "爱": {"a.txt"}}},
+		{[]string{"a.txt", "b.txt"}, []map[string]int{{"love": 1}, {"love": 3, "amor": 1}}, map[string][]string{"love": {"a.txt", "b.txt"}, "amor": {"b.txt"}}},
+		{[]string{"a.txt", "a.txt"}, []map[string]int{{"love": 1}, {"love": 1}}, map[string][]string{"love": {"a.txt"}}},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := make(map[string][]string)
+		for i, source := range testCase.Sources {
+			AddProvenance(given, testCase.Maps[i], source)
+		}
+
+		equal := len(given) == len(testCase.Output)
+		for item, sources := range testCase.Output {
+			equal = equal && CheckAreArraysEqual(given[item], sources)
+		}
+		if !equal {
+			t.Errorf("AddProvenance(%v, %v) = %v; want %v", testCase.Sources, testCase.Maps, given, testCase.Output)
+		}
+	}
+}
+
 // Unit Test for ReadJSONToArray()
 func TestReadJSONToArray(t *testing.T) {
 	// Define a test case struct
@@ -546,6 +2315,37 @@ func TestCheckHexString(t *testing.T) {
 	}
 }
 
+// Unit Test for DecodeHexString()
+func TestDecodeHexString(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"$HEX[6c6f7665]", "love"},
+		{"$HEX[e6b581]", "\xe6\xb5\x81"},
+		{"$HEX[636166e9]", "caf\xe9"},
+		{"$HEX[]", ""},
+		{"$HEX[6c6f766]", "$HEX[6c6f766]"},
+		{"$HEX[zz]", "$HEX[zz]"},
+		{"I 爱 you", "I 爱 you"},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := DecodeHexString(testCase.Input)
+		if given != testCase.Output {
+			t.Errorf("DecodeHexString(%v) = %q; want %q", testCase.Input, given, testCase.Output)
+		}
+	}
+}
+
 // Unit Test for CheckAreMapsEqual()
 func TestCheckAreMapsEqual(t *testing.T) {
 
@@ -627,6 +2427,129 @@ func TestCheckAreArraysEqual(t *testing.T) {
 	}
 }
 
+// Unit Test for IsParsableText()
+func TestIsParsableText(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input       string
+		ParsingMode int
+		Output      bool
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"Hello World, again.", 0, true},
+		{"Café crème à Zürich!", 0, true},
+		{"東京は晴れです。明日は雨、でしょう？", 0, true},
+		{"नमस्ते दुनिया।", 0, true},
+		{"Don't stop-now", 0, false},
+		{"Don't stop-now", 1, true},
+		{"«Bonjour» — «Salut»", 1, true},
+		{"Call (555) 0100", 1, false},
+		{"<p>tag</p>", 2, false},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := IsParsableText(testCase.Input, testCase.ParsingMode, DefaultPunctuation)
+		if given != testCase.Output {
+			t.Errorf("IsParsableText(%v, %v) = %v; want %v", testCase.Input, testCase.ParsingMode, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for SplitSentences()
+func TestSplitSentences(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"Hello World. Acme Corporation", []string{"Hello World", "Acme Corporation"}},
+		{"Visit example.com for v2.5 now!! Really?", []string{"Visit example.com for v2.5 now", "Really"}},
+		{`She said "go." Then left.`, []string{`She said "go`, "Then left"}},
+		{"東京は晴れです。明日は雨でしょう！", []string{"東京は晴れです", "明日は雨でしょう"}},
+		{"नमस्ते दुनिया। आप कैसे हैं?", []string{"नमस्ते दुनिया", "आप कैसे हैं"}},
+		{"  ...  ", nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := SplitSentences(testCase.Input)
+		if !CheckAreArraysEqual(given, testCase.Output) {
+			t.Errorf("SplitSentences(%v) = %q; want %q", testCase.Input, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for SplitPhrases()
+func TestSplitPhrases(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input       string
+		Punctuation string
+		Output      []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"Summer time, winter time; spring", DefaultPunctuation, []string{"Summer time", "winter time", "spring"}},
+		{"北京，上海、广州", DefaultPunctuation, []string{"北京", "上海", "广州"}},
+		{"Summer time, winter time; spring", ";", []string{"Summer time, winter time", "spring"}},
+		{"one | two", "|", []string{"one", "two"}},
+		{",,", DefaultPunctuation, nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := SplitPhrases(testCase.Input, testCase.Punctuation)
+		if !CheckAreArraysEqual(given, testCase.Output) {
+			t.Errorf("SplitPhrases(%v, %v) = %q; want %q", testCase.Input, testCase.Punctuation, given, testCase.Output)
+		}
+	}
+}
+
+// Unit Test for SplitWords()
+func TestSplitWords(t *testing.T) {
+
+	// Define a test case struct
+	type TestCase struct {
+		Input  string
+		Output []string
+	}
+
+	type TestCases []TestCase
+
+	// Define test cases
+	testCases := TestCases{
+		{"Hello  World", []string{"Hello", "World"}},
+		{"«Bonjour» l’été, #summer", []string{"Bonjour", "l’été", "l", "été", "#summer"}},
+		{"Don't stop-now (please)", []string{"Don't", "Don", "t", "stop-now", "stop", "now", "please"}},
+		{"Zürich　Genève", []string{"Zürich", "Genève"}},
+		{"- ...", nil},
+	}
+
+	// Run test cases
+	for _, testCase := range testCases {
+		given := SplitWords(testCase.Input)
+		if !CheckAreArraysEqual(given, testCase.Output) {
+			t.Errorf("SplitWords(%v) = %q; want %q", testCase.Input, given, testCase.Output)
+		}
+	}
+}
+
 // Unit Test for GenerateNGrams()
 func TestGenerateNGrams(t *testing.T) {
 